@@ -6,54 +6,155 @@ import (
 )
 
 type arguments struct {
-	CwdMode                *string
-	CwdMaxDepth            *int
-	CwdMaxDirSize          *int
-	ColorizeHostname       *bool
-	HostnameOnlyIfSSH      *bool
-	SshAlternateIcon       *bool
-	EastAsianWidth         *bool
-	PromptOnNewLine        *bool
-	StaticPromptIndicator  *bool
-	VenvNameSizeLimit      *int
-	GitAssumeUnchangedSize *int64
-	GitDisableStats        *string
-	GitMode                *string
-	Jobs                   *int
-	Mode                   *string
-	Theme                  *string
-	Shell                  *string
-	Modules                *string
-	ModulesRight           *string
-	Priority               *string
-	MaxWidthPercentage     *int
-	TruncateSegmentWidth   *int
-	PrevError              *int
-	NumericExitCodes       *bool
-	IgnoreRepos            *string
-	ShortenGKENames        *bool
-	ShortenEKSNames        *bool
-	ShortenOpenshiftNames  *bool
-	ShellVar               *string
-	ShellVarNoWarnEmpty    *bool
-	TrimADDomain           *bool
-	PathAliases            *string
-	Duration               *string
-	DurationMin            *string
-	DurationLowPrecision   *bool
-	Eval                   *bool
-	Condensed              *bool
-	IgnoreWarnings         *bool
-	Time                   *string
-	ViMode                 *string
+	ConfigFiles               *configFiles
+	CwdMode                   *string
+	CwdMaxDepth               *int
+	CwdMaxDirSize             *int
+	ColorizeHostname          *bool
+	HostnameOnlyIfSSH         *bool
+	SshAlternateIcon          *bool
+	EastAsianWidth            *bool
+	PromptOnNewLine           *bool
+	StaticPromptIndicator     *bool
+	VenvNameSizeLimit         *int
+	GitAssumeUnchangedSize    *int64
+	GitDisableStats           *string
+	GitMode                   *string
+	Jobs                      *int
+	Mode                      *string
+	Theme                     *string
+	Shell                     *string
+	Modules                   *string
+	ModulesRight              *string
+	Priority                  *string
+	SegmentPriorities         *string
+	SegmentMinWidths          *string
+	MaxWidthPercentage        *int
+	TruncateSegmentWidth      *int
+	PrevError                 *int
+	CommandNumber             *int
+	NumericExitCodes          *bool
+	IgnoreRepos               *string
+	ShortenGKENames           *bool
+	ShortenEKSNames           *bool
+	ShortenOpenshiftNames     *bool
+	ShellVar                  *string
+	ShellVarNoWarnEmpty       *bool
+	TrimADDomain              *bool
+	PathAliases               *string
+	Duration                  *string
+	DurationMin               *string
+	DurationLowPrecision      *bool
+	Eval                      *bool
+	Condensed                 *bool
+	IgnoreWarnings            *bool
+	Time                      *string
+	ViMode                    *string
+	GitSyncGlyphMode          *bool
+	GitUntrackedDirAsSingle   *bool
+	GitShowIgnoredCount       *bool
+	AsdfTools                 *string
+	TemplateSegment           *string
+	GitDetachedFormat         *string
+	GitHideInHome             *bool
+	GitDetachedAheadBehind    *bool
+	GitHideWhenClean          *bool
+	Output                    *string
+	GitShowSubmoduleCount     *bool
+	NerdFontVersion           *int
+	GitShowPushAheadBehind    *bool
+	Debug                     *bool
+	TitleFormat               *string
+	GitStatsThinSeparator     *bool
+	GitShowRepoType           *bool
+	GitStashFileCount         *bool
+	GitShowGerrit             *bool
+	EvalSafe                  *bool
+	GitBranchBasenameOnly     *bool
+	GitAheadBehindCap         *int
+	GitFSMonitor              *bool
+	GitShowRemoteName         *bool
+	KubeShowHelm              *bool
+	GitStatsSharedBg          *int
+	VaultCheckToken           *bool
+	ExitSticky                *bool
+	GitDivergedGlyph          *bool
+	CwdGradient               *bool
+	GitShowBehindDefault      *bool
+	Reverse                   *bool
+	GitPushTargetBadge        *bool
+	GitCLIFallbackOnError     *bool
+	GitStashClassify          *bool
+	BuildStatusFile           *string
+	UserhostFormat            *string
+	DefaultUser               *string
+	HideDefaultUser           *bool
+	GitShowCommitAge          *bool
+	GitAgeDate                *string
+	GitShowUnpushedIndicator  *bool
+	GitConflictProminent      *bool
+	CwdIgnoreErrors           *bool
+	CwdNativeToken            *bool
+	PythonPrefer              *string
+	GitRecurseSubmodules      *bool
+	GitBranchColorBySync      *bool
+	CwdHeadCount              *int
+	CwdTailCount              *int
+	GitUntrackedNotDirty      *bool
+	GitShowGCHint             *bool
+	GitGCHintThreshold        *int
+	GCPShowAccount            *bool
+	GCPConfigFilesOnly        *bool
+	GitShowNoUpstream         *bool
+	GitStatsBeforeBranch      *bool
+	GitSymbolBranch           *string
+	GitSymbolDetached         *string
+	GitSymbolAhead            *string
+	GitSymbolBehind           *string
+	GitSymbolStaged           *string
+	GitSymbolNotStaged        *string
+	GitSymbolUntracked        *string
+	GitSymbolConflicted       *string
+	GitSymbolStashed          *string
+	Side                      *string
+	GithubCacheFile           *string
+	GithubCacheMaxAge         *int
+	ColorMode                 *string
+	DirEnteredAt              *string
+	GitShowMergeBase          *bool
+	GitMergeBaseRef           *string
+	GitShowNested             *bool
+	FillToWidth               *bool
+	FillChar                  *string
+	MultilineContinuation     *string
+	LastCommand               *string
+	LastCommandMaxLength      *int
+	GitSkipOnFS               *string
+	GitDetectLock             *bool
+	MuxStates                 *string
+	Group                     *string
+	GitSubmodulePointers      *bool
+	CwdMaxLength              *int
+	GitShowCommitSubject      *bool
+	GitCommitSubjectMaxLength *int
+	PostHook                  *string
+	GitUpstreamFallback       *bool
+	GitShowRemoteCount        *bool
+	GitLiteStatus             *bool
 }
 
 var args = arguments{
+	ConfigFiles: func() *configFiles {
+		files := &configFiles{}
+		flag.Var(files, "config", comments("Config file to load instead of ~/.config/powerline-go/config.json. May be given multiple times,",
+			"later files are merged over earlier ones. A file may set \"list-merge\": {\"modules\": \"append\"} to extend lists instead of replacing them."))
+		return files
+	}(),
 	CwdMode: flag.String(
 		"cwd-mode",
 		defaults.CwdMode,
 		commentsWithDefaults("How to display the current directory",
-			"(valid choices: fancy, semifancy, plain, dironly)")),
+			"(valid choices: fancy, semifancy, plain, dironly, head-tail, unique-prefix)")),
 	CwdMaxDepth: flag.Int(
 		"cwd-max-depth",
 		defaults.CwdMaxDepth,
@@ -107,12 +208,12 @@ var args = arguments{
 		"git-mode",
 		defaults.GitMode,
 		commentsWithDefaults("How to display git status",
-			"(valid choices: fancy, compact, simple)")),
+			"(valid choices: fancy, compact, simple, counts, sync, micro, dot)")),
 	Mode: flag.String(
 		"mode",
 		defaults.Mode,
 		commentsWithDefaults("The characters used to make separators between segments.",
-			"(valid choices: patched, compatible, flat)")),
+			"(valid choices: patched, compatible, flat, ascii, nerd-font-v2, nerd-font-v3)")),
 	Theme: flag.String(
 		"theme",
 		defaults.Theme,
@@ -127,19 +228,30 @@ var args = arguments{
 		"modules",
 		strings.Join(defaults.Modules, ","),
 		commentsWithDefaults("The list of modules to load, separated by ','",
-			"(valid choices: aws, bzr, cwd, direnv, docker, docker-context, dotenv, duration, exit, fossil, gcp, git, gitlite, goenv, hg, host, jobs, kube, load, newline, nix-shell, node, perlbrew, perms, plenv, rbenv, root, rvm, shell-var, shenv, ssh, svn, termtitle, terraform-workspace, time, user, venv, vgo, vi-mode, wsl)",
+			"(valid choices: asdf, aws, azure, build-status, bzr, chezmoi, container, cwd, direnv, dirtime, docker, docker-context, dotenv, duration, editor, exit, fossil, gcp, git, github, gitlite, goenv, hg, host, jobs, kube, lastcmd, load, mux-state, newline, nix-shell, node, perlbrew, perms, plenv, pyenv, rbenv, root, rvm, shell-var, shenv, ssh, sshagent, svn, template, termtitle, terraform-workspace, time, todo, user, userhost, vault, venv, vgo, vi-mode, wsl)",
 			"Unrecognized modules will be invoked as 'powerline-go-MODULE' executable plugins and should output a (possibly empty) list of JSON objects that unmarshal to powerline-go's Segment structs.")),
 	ModulesRight: flag.String(
 		"modules-right",
 		strings.Join(defaults.ModulesRight, ","),
 		comments("The list of modules to load anchored to the right, for shells that support it, separated by ','",
-			"(valid choices: aws, bzr, cwd, direnv, docker, docker-context, dotenv, duration, exit, fossil, gcp, git, gitlite, goenv, hg, host, jobs, kube, load, newline, nix-shell, node, perlbrew, perms, plenv, rbenv, root, rvm, shell-var, shenv, ssh, svn, termtitle, terraform-workspace, time, user, venv, vgo, wsl)",
+			"(valid choices: asdf, aws, azure, build-status, bzr, chezmoi, container, cwd, direnv, dirtime, docker, docker-context, dotenv, duration, editor, exit, fossil, gcp, git, github, gitlite, goenv, hg, host, jobs, kube, lastcmd, load, mux-state, newline, nix-shell, node, perlbrew, perms, plenv, pyenv, rbenv, root, rvm, shell-var, shenv, ssh, sshagent, svn, template, termtitle, terraform-workspace, time, todo, user, userhost, vault, venv, vgo, wsl)",
 			"Unrecognized modules will be invoked as 'powerline-go-MODULE' executable plugins and should output a (possibly empty) list of JSON objects that unmarshal to powerline-go's Segment structs.")),
 	Priority: flag.String(
 		"priority",
 		strings.Join(defaults.Priority, ","),
 		commentsWithDefaults("Segments sorted by priority, if not enough space exists, the least priorized segments are removed first. Separate with ','",
-			"(valid choices: aws, bzr, cwd, direnv, docker, docker-context, dotenv, duration, exit, fossil, gcp, git, gitlite, goenv, hg, host, jobs, kube, load, newline, nix-shell, node, perlbrew, perms, plenv, rbenv, root, rvm, shell-var, shenv, ssh, svn, termtitle, terraform-workspace, time, user, venv, vgo, vi-mode, wsl)")),
+			"(valid choices: asdf, aws, azure, build-status, bzr, chezmoi, container, cwd, direnv, dirtime, docker, docker-context, dotenv, duration, editor, exit, fossil, gcp, git, github, gitlite, goenv, hg, host, jobs, kube, lastcmd, load, mux-state, newline, nix-shell, node, perlbrew, perms, plenv, pyenv, rbenv, root, rvm, shell-var, shenv, ssh, sshagent, svn, template, termtitle, terraform-workspace, time, todo, user, userhost, vault, venv, vgo, vi-mode, wsl)")),
+	SegmentPriorities: flag.String(
+		"segment-priorities",
+		"",
+		comments("Explicit priorities for individual segments, overriding their position in -priority. Separate with ','.",
+			"Specify these as key/value pairs like cwd=100,git-status=5.",
+			"Segments with a higher priority are dropped last when the prompt is too wide, regardless of their render order.")),
+	SegmentMinWidths: flag.String(
+		"segment-min-width",
+		"",
+		comments("Minimum terminal widths for individual segments, a segment is omitted when the terminal is narrower. Separate with ','.",
+			"Specify these as key/value pairs like host:100,git:60.")),
 	MaxWidthPercentage: flag.Int(
 		"max-width",
 		defaults.MaxWidthPercentage,
@@ -152,6 +264,10 @@ var args = arguments{
 		"error",
 		defaults.PrevError,
 		comments("Exit code of previously executed command")),
+	CommandNumber: flag.Int(
+		"command-number",
+		defaults.CommandNumber,
+		comments("Number of the previously executed command, e.g. $HISTCMD, which -exit-sticky uses to tell new commands from redrawn prompts")),
 	NumericExitCodes: flag.Bool(
 		"numeric-exit-codes",
 		defaults.NumericExitCodes,
@@ -226,4 +342,390 @@ var args = arguments{
 		"vi-mode",
 		defaults.ViMode,
 		comments("The current vi-mode (eg. KEYMAP for zsh) for vi-module module")),
+	GitSyncGlyphMode: flag.Bool(
+		"git-sync-glyph-mode",
+		defaults.GitSyncGlyphMode,
+		comments("Show a single glyph for the upstream sync state (ahead, behind, diverged, synced) instead of ahead/behind counts")),
+	GitUntrackedDirAsSingle: flag.Bool(
+		"git-untracked-dir-as-single",
+		defaults.GitUntrackedDirAsSingle,
+		comments("Count an untracked directory as a single untracked entry instead of every file inside it,",
+			"even if status.showUntrackedFiles is set to all. Improves performance with large untracked directories")),
+	GitShowIgnoredCount: flag.Bool(
+		"git-show-ignored-count",
+		defaults.GitShowIgnoredCount,
+		comments("Show the number of tracked files that match ignore rules and are modified")),
+	AsdfTools: flag.String(
+		"asdf-tools",
+		strings.Join(defaults.AsdfTools, ","),
+		comments("Comma-separated list of tools from .tool-versions to show in the asdf segment (defaults to all tools)")),
+	TemplateSegment: flag.String(
+		"template-segment",
+		defaults.TemplateSegment,
+		comments("A Go text/template rendered by the template module, see TemplateContext for the available data.",
+			"Example: '{{.Username}} on {{.Branch}}'")),
+	GitDetachedFormat: flag.String(
+		"git-detached-format",
+		defaults.GitDetachedFormat,
+		commentsWithDefaults("How to display a detached HEAD in the git segment",
+			"(placeholders: {short}, {full}, {tag})")),
+	GitHideInHome: flag.Bool(
+		"git-hide-in-home",
+		defaults.GitHideInHome,
+		comments("Hide the git segments when the current directory is exactly the home directory")),
+	GitDetachedAheadBehind: flag.Bool(
+		"git-detached-ahead-behind",
+		defaults.GitDetachedAheadBehind,
+		comments("On a detached HEAD, show ahead/behind counts against the upstream of the first local branch containing HEAD")),
+	GitHideWhenClean: flag.Bool(
+		"git-hide-when-clean",
+		defaults.GitHideWhenClean,
+		comments("Hide the git segment when the repository is clean and in sync with its upstream")),
+	Output: flag.String(
+		"output",
+		defaults.Output,
+		commentsWithDefaults("How to output the prompt",
+			"(valid choices: prompt, json)")),
+	GitShowSubmoduleCount: flag.Bool(
+		"git-show-submodule-count",
+		defaults.GitShowSubmoduleCount,
+		comments("Show the number of submodules registered in .gitmodules")),
+	NerdFontVersion: flag.Int(
+		"nerd-font-version",
+		defaults.NerdFontVersion,
+		comments("Use Nerd Font glyphs for the given major version of Nerd Fonts, overriding -mode. Setting this to 0 disables it.",
+			"(valid choices: 0, 2, 3)")),
+	GitShowPushAheadBehind: flag.Bool(
+		"git-show-push-ahead-behind",
+		defaults.GitShowPushAheadBehind,
+		comments("Also show ahead/behind counts against the push target (branch.<name>.pushRemote or remote.pushDefault) when it differs from the upstream")),
+	Debug: flag.Bool(
+		"debug",
+		defaults.Debug,
+		comments("Print debugging information, such as how long each segment took, to stderr")),
+	TitleFormat: flag.String(
+		"title-format",
+		defaults.TitleFormat,
+		comments("The terminal title set by the termtitle segment instead of the shell's own user@host: cwd",
+			"(placeholders: {cwd}, {branch}, {host}, {user})")),
+	GitStatsThinSeparator: flag.Bool(
+		"git-stats-thin-separator",
+		defaults.GitStatsThinSeparator,
+		comments("Use a thin separator between the git branch and its status segments in fancy mode")),
+	GitShowRepoType: flag.Bool(
+		"git-show-repo-type",
+		defaults.GitShowRepoType,
+		comments("Show an icon before the git branch for bare repositories, submodules, linked worktrees and sparse checkouts")),
+	GitStashFileCount: flag.Bool(
+		"git-stash-file-count",
+		defaults.GitStashFileCount,
+		comments("Show the number of files changed by the most recent stash next to the stash count")),
+	GitShowGerrit: flag.Bool(
+		"git-show-gerrit",
+		defaults.GitShowGerrit,
+		comments("Show the short Gerrit Change-Id of the HEAD commit next to the git branch")),
+	EvalSafe: flag.Bool(
+		"eval-safe",
+		defaults.EvalSafe,
+		comments("Escape dynamic content like branch names and directories for the target shell so they can't inject commands or break the prompt quoting")),
+	GitBranchBasenameOnly: flag.Bool(
+		"git-branch-basename-only",
+		defaults.GitBranchBasenameOnly,
+		comments("Only show the last component of slash-separated branch names, e.g. thing for feature/sub/thing")),
+	GitAheadBehindCap: flag.Int(
+		"git-ahead-behind-cap",
+		defaults.GitAheadBehindCap,
+		commentsWithDefaults("Stop counting ahead/behind commits above this value and show them as e.g. 1000+. Setting this to 0 counts all commits.",
+			"A cap passes --no-ahead-behind to git status, which needs git 2.17 or later")),
+	GitFSMonitor: flag.Bool(
+		"git-fsmonitor",
+		defaults.GitFSMonitor,
+		comments("Keep scanning for untracked files in large repositories, see -git-assume-unchanged-size, if they have both",
+			"core.fsmonitor and core.untrackedCache (or feature.manyFiles) set, which let git status skip the full scan")),
+	GitShowRemoteName: flag.Bool(
+		"git-show-remote-name",
+		defaults.GitShowRemoteName,
+		comments("Show the name of the remote the current branch tracks next to the git branch")),
+	KubeShowHelm: flag.Bool(
+		"kube-show-helm",
+		defaults.KubeShowHelm,
+		comments("Show $HELM_NAMESPACE in the kube segment if it differs from the Kubernetes namespace")),
+	GitStatsSharedBg: flag.Int(
+		"git-stats-shared-bg",
+		defaults.GitStatsSharedBg,
+		comments("Use this background color for all git status segments, keeping their foreground colors. Setting this to -1 uses the theme colors.")),
+	VaultCheckToken: flag.Bool(
+		"vault-check-token",
+		defaults.VaultCheckToken,
+		comments("Check whether $VAULT_TOKEN is still valid with a request to $VAULT_ADDR in the vault segment")),
+	ExitSticky: flag.Bool(
+		"exit-sticky",
+		defaults.ExitSticky,
+		comments("Keep showing the last non-zero exit code, dimmed, on the following prompts until a command succeeds.",
+			"Needs -command-number to tell prompts after a new command from redrawn ones")),
+	GitDivergedGlyph: flag.Bool(
+		"git-diverged-glyph",
+		defaults.GitDivergedGlyph,
+		comments("Show a single diverged token like \u21D52/3 instead of separate ahead and behind counts when a branch is both ahead and behind")),
+	CwdGradient: flag.Bool(
+		"cwd-gradient",
+		defaults.CwdGradient,
+		comments("Color the directories of the cwd segment in a gradient from the root to the current directory")),
+	GitShowBehindDefault: flag.Bool(
+		"git-show-behind-default",
+		defaults.GitShowBehindDefault,
+		comments("Show how far the current branch is behind the default branch of origin, as set by origin/HEAD")),
+	Reverse: flag.Bool(
+		"reverse",
+		defaults.Reverse,
+		comments("Draw the segments of each line in reverse order, with separators pointing the other way")),
+	GitPushTargetBadge: flag.Bool(
+		"git-push-target-badge",
+		defaults.GitPushTargetBadge,
+		comments("Show the remote branch git push would push the current branch to")),
+	GitCLIFallbackOnError: flag.Bool(
+		"git-cli-fallback-on-error",
+		defaults.GitCLIFallbackOnError,
+		comments("Run git status when go-git can't read the index for -git-lite-status")),
+	GitStashClassify: flag.Bool(
+		"git-stash-classify",
+		defaults.GitStashClassify,
+		comments("Count stashes created with a message separately from automatic WIP stashes")),
+	BuildStatusFile: flag.String(
+		"build-status-file",
+		defaults.BuildStatusFile,
+		comments("File with the status of the last build, either ok or fail,", "relative to the repository root unless absolute")),
+	UserhostFormat: flag.String(
+		"userhost-format",
+		defaults.UserhostFormat,
+		comments("Format of the userhost segment, {user} and {host} are replaced by the username and hostname")),
+	DefaultUser: flag.String(
+		"default-user",
+		defaults.DefaultUser,
+		comments("Your usual username, which the userhost segment leaves out and -hide-default-user hides.",
+			"Defaults to $DEFAULT_USER")),
+	HideDefaultUser: flag.Bool(
+		"hide-default-user",
+		defaults.HideDefaultUser,
+		comments("Hide the user segment when logged in as the default user")),
+	GitShowCommitAge: flag.Bool(
+		"git-show-commit-age",
+		defaults.GitShowCommitAge,
+		comments("Show how long ago the checked out commit was made")),
+	GitAgeDate: flag.String(
+		"git-age-date",
+		defaults.GitAgeDate,
+		commentsWithDefaults("Which date of the commit -git-show-commit-age uses",
+			"(valid choices: author, commit)")),
+	GitShowUnpushedIndicator: flag.Bool(
+		"git-show-unpushed-indicator",
+		defaults.GitShowUnpushedIndicator,
+		comments("Warn when the checked out commit isn't on any remote-tracking branch")),
+	GitConflictProminent: flag.Bool(
+		"git-conflict-prominent",
+		defaults.GitConflictProminent,
+		comments("Show the number of conflicted files first, in a warning color")),
+	CwdIgnoreErrors: flag.Bool(
+		"cwd-ignore-errors",
+		defaults.CwdIgnoreErrors,
+		comments("Mark a deleted current directory in the cwd segment instead of warning,", "and skip the segments that need the directory to exist")),
+	CwdNativeToken: flag.Bool(
+		"cwd-native-token",
+		defaults.CwdNativeToken,
+		comments("Let the shell fill in the current directory, so the prompt follows cd without rerunning powerline-go.",
+			"Supported by bash and zsh, other shells show the directory as usual")),
+	PythonPrefer: flag.String(
+		"python-prefer",
+		defaults.PythonPrefer,
+		commentsWithDefaults("Which segment shows the Python environment when both venv and pyenv have one",
+			"(valid choices: venv, pyenv)")),
+	GitRecurseSubmodules: flag.Bool(
+		"git-recurse-submodules",
+		defaults.GitRecurseSubmodules,
+		comments("Include the changes inside submodules, recursively, in the git status counts. Slow with many submodules")),
+	GitBranchColorBySync: flag.Bool(
+		"git-branch-color-by-sync",
+		defaults.GitBranchColorBySync,
+		comments("Color the branch by whether it is ahead, behind, diverged from or in sync with its upstream, instead of by changes")),
+	CwdHeadCount: flag.Int(
+		"cwd-head-count",
+		defaults.CwdHeadCount,
+		comments("Number of leading directories -cwd-mode=head-tail keeps")),
+	CwdTailCount: flag.Int(
+		"cwd-tail-count",
+		defaults.CwdTailCount,
+		comments("Number of trailing directories -cwd-mode=head-tail keeps")),
+	GitUntrackedNotDirty: flag.Bool(
+		"git-untracked-not-dirty",
+		defaults.GitUntrackedNotDirty,
+		comments("Keep the branch colored as clean when the only changes are untracked files")),
+	GitShowGCHint: flag.Bool(
+		"git-show-gc-hint",
+		defaults.GitShowGCHint,
+		comments("Hint that the repository needs git gc when it has many loose objects")),
+	GitGCHintThreshold: flag.Int(
+		"git-gc-hint-threshold",
+		defaults.GitGCHintThreshold,
+		comments("Number of loose objects above which -git-show-gc-hint shows, like gc.auto. Estimated from objects/17 like git gc --auto does")),
+	GCPShowAccount: flag.Bool(
+		"gcp-show-account",
+		defaults.GCPShowAccount,
+		comments("Show the account of the active gcloud configuration next to the project in the gcp segment")),
+	GCPConfigFilesOnly: flag.Bool(
+		"gcp-config-files-only",
+		defaults.GCPConfigFilesOnly,
+		comments("Only read the gcloud configuration files in the gcp segment instead of falling back to running gcloud")),
+	GitShowNoUpstream: flag.Bool(
+		"git-show-no-upstream",
+		defaults.GitShowNoUpstream,
+		comments("Mark branches without an upstream, which were likely never pushed")),
+	GitStatsBeforeBranch: flag.Bool(
+		"git-stats-before-branch",
+		defaults.GitStatsBeforeBranch,
+		comments("Show the git status before the branch instead of after it")),
+	GitSymbolBranch: flag.String(
+		"git-symbol-branch",
+		defaults.GitSymbolBranch,
+		comments("Symbol for branch in the git segments, overriding the one of -mode")),
+	GitSymbolDetached: flag.String(
+		"git-symbol-detached",
+		defaults.GitSymbolDetached,
+		comments("Symbol for detached HEAD in the git segments, overriding the one of -mode")),
+	GitSymbolAhead: flag.String(
+		"git-symbol-ahead",
+		defaults.GitSymbolAhead,
+		comments("Symbol for ahead in the git segments, overriding the one of -mode")),
+	GitSymbolBehind: flag.String(
+		"git-symbol-behind",
+		defaults.GitSymbolBehind,
+		comments("Symbol for behind in the git segments, overriding the one of -mode")),
+	GitSymbolStaged: flag.String(
+		"git-symbol-staged",
+		defaults.GitSymbolStaged,
+		comments("Symbol for staged in the git segments, overriding the one of -mode")),
+	GitSymbolNotStaged: flag.String(
+		"git-symbol-not-staged",
+		defaults.GitSymbolNotStaged,
+		comments("Symbol for not staged in the git segments, overriding the one of -mode")),
+	GitSymbolUntracked: flag.String(
+		"git-symbol-untracked",
+		defaults.GitSymbolUntracked,
+		comments("Symbol for untracked in the git segments, overriding the one of -mode")),
+	GitSymbolConflicted: flag.String(
+		"git-symbol-conflicted",
+		defaults.GitSymbolConflicted,
+		comments("Symbol for conflicted in the git segments, overriding the one of -mode")),
+	GitSymbolStashed: flag.String(
+		"git-symbol-stashed",
+		defaults.GitSymbolStashed,
+		comments("Symbol for stashed in the git segments, overriding the one of -mode")),
+	Side: flag.String(
+		"side",
+		defaults.Side,
+		commentsWithDefaults("Which part of the prompt to draw. Use right for shells with a separate right prompt command, like fish_right_prompt",
+			"(valid choices: left, right)")),
+	GithubCacheFile: flag.String(
+		"github-cache-file",
+		defaults.GithubCacheFile,
+		comments("File with the number of pull requests waiting for you, written by an external periodic job",
+			"(default: powerline-go/github-prs in the user cache directory)")),
+	GithubCacheMaxAge: flag.Int(
+		"github-cache-max-age",
+		defaults.GithubCacheMaxAge,
+		commentsWithDefaults("Seconds after which the github cache file is considered stale and hidden, 0 disables the check")),
+	ColorMode: flag.String(
+		"color-mode",
+		defaults.ColorMode,
+		commentsWithDefaults("How many colors the terminal supports. Themes are mapped to the nearest basic colors on 8 and 16 color terminals,",
+			"auto asks terminfo for the colors of $TERM",
+			"except for plain xterm, which is assumed to support 256 colors",
+			"(valid choices: auto, 8, 16, 256, true)")),
+	DirEnteredAt: flag.String(
+		"dir-entered-at",
+		defaults.DirEnteredAt,
+		comments("Unix timestamp of when the current directory was entered, set by a chpwd hook of the shell")),
+	GitShowMergeBase: flag.Bool(
+		"git-show-merge-base",
+		defaults.GitShowMergeBase,
+		comments("Show the short hash of the merge-base of HEAD and its upstream, or of -git-merge-base-ref")),
+	GitMergeBaseRef: flag.String(
+		"git-merge-base-ref",
+		defaults.GitMergeBaseRef,
+		comments("Ref to compute the merge-base of -git-show-merge-base against instead of the upstream of the current branch")),
+	GitShowNested: flag.Bool(
+		"git-show-nested",
+		defaults.GitShowNested,
+		comments("Also show the branch of the repository the current one is nested in, if any")),
+	FillToWidth: flag.Bool(
+		"fill-to-width",
+		defaults.FillToWidth,
+		comments("Fill the rest of each line of the prompt followed by a line break up to the terminal width,",
+			"for use with -newline or -prompt-on-newline, and the last line up to the right prompt of -modules-right")),
+	FillChar: flag.String(
+		"fill-char",
+		defaults.FillChar,
+		commentsWithDefaults("Character -fill-to-width fills lines with")),
+	MultilineContinuation: flag.String(
+		"multiline-continuation",
+		defaults.MultilineContinuation,
+		comments("Glyph to start every line of the prompt after the first with, like '└ '")),
+	LastCommand: flag.String(
+		"last-command",
+		defaults.LastCommand,
+		comments("The previous command line, of which the lastcmd segment shows the command name")),
+	LastCommandMaxLength: flag.Int(
+		"last-command-max-length",
+		defaults.LastCommandMaxLength,
+		commentsWithDefaults("Maximum width of the command name shown by the lastcmd segment")),
+	GitSkipOnFS: flag.String(
+		"git-skip-on-fs",
+		strings.Join(defaults.GitSkipOnFS, ","),
+		comments("Filesystem types, like nfs,cifs,smb2, on which the git segment only reads the branch from .git/HEAD",
+			"instead of running git. Separate with ','")),
+	GitDetectLock: flag.Bool(
+		"git-detect-lock",
+		defaults.GitDetectLock,
+		comments("Show when another git process holds the index lock, and skip git status while it does")),
+	MuxStates: flag.String(
+		"mux-states",
+		strings.Join(defaults.MuxStates, ","),
+		commentsWithDefaults("Which states of the tmux pane the mux-state segment shows. Separate with ','",
+			"(valid choices: zoomed, copy-mode)")),
+	Group: flag.String(
+		"group",
+		strings.Join(defaults.Group, ","),
+		comments("Modules to draw as one block on a shared background when they are next to each other. Separate with ','")),
+	GitSubmodulePointers: flag.Bool(
+		"git-submodule-pointers",
+		defaults.GitSubmodulePointers,
+		comments("Count submodules pointing to a different commit as staged or not staged changes")),
+	CwdMaxLength: flag.Int(
+		"cwd-max-length",
+		defaults.CwdMaxLength,
+		commentsWithDefaults("Maximum number of columns taken up by the path, counting wide characters as two columns (0 means unlimited)")),
+	GitShowCommitSubject: flag.Bool(
+		"git-show-commit-subject",
+		defaults.GitShowCommitSubject,
+		comments("Show the first line of the message of the HEAD commit after the branch")),
+	GitCommitSubjectMaxLength: flag.Int(
+		"git-commit-subject-max-length",
+		defaults.GitCommitSubjectMaxLength,
+		commentsWithDefaults("Maximum number of columns of the commit subject shown by -git-show-commit-subject (0 means unlimited)")),
+	PostHook: flag.String(
+		"post-hook",
+		defaults.PostHook,
+		comments("Script receiving the rendered prompt, or the JSON segment list with -output json, on stdin",
+			"and printing the prompt to use instead")),
+	GitUpstreamFallback: flag.Bool(
+		"git-upstream-fallback",
+		defaults.GitUpstreamFallback,
+		comments("Compare branches without an upstream to the branch of the same name on origin, or another remote")),
+	GitShowRemoteCount: flag.Bool(
+		"git-show-remote-count",
+		defaults.GitShowRemoteCount,
+		comments("Show the number of remotes configured, if there is more than one")),
+	GitLiteStatus: flag.Bool(
+		"git-lite-status",
+		defaults.GitLiteStatus,
+		comments("Check the work tree in the git-lite segment, coloring the branch when it is dirty. This scans the whole work tree")),
 }
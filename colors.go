@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// basicPalette holds the RGB values xterm uses for the 16 basic ANSI colors,
+// which 256 color indices are matched against on terminals without them
+var basicPalette = [16][3]int{
+	{0, 0, 0},
+	{205, 0, 0},
+	{0, 205, 0},
+	{205, 205, 0},
+	{0, 0, 238},
+	{205, 0, 205},
+	{0, 205, 205},
+	{229, 229, 229},
+	{127, 127, 127},
+	{255, 0, 0},
+	{0, 255, 0},
+	{255, 255, 0},
+	{92, 92, 255},
+	{255, 0, 255},
+	{0, 255, 255},
+	{255, 255, 255},
+}
+
+// cubeLevels are the channel intensities of the 6x6x6 color cube at 16-231
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// colorRGB returns the RGB value of a 256 color index
+func colorRGB(code uint8) [3]int {
+	switch {
+	case code < 16:
+		return basicPalette[code]
+	case code < 232:
+		i := int(code) - 16
+		return [3]int{cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6]}
+	default:
+		gray := 8 + 10*(int(code)-232)
+		return [3]int{gray, gray, gray}
+	}
+}
+
+// isGray reports whether the basic color i is black, white or a gray
+func isGray(i int) bool {
+	return i == 0 || i == 7 || i == 8 || i == 15
+}
+
+// quantizeColor maps a 256 color index to the nearest of the first n basic
+// colors, n being 8 or 16. Grays only map to grays, which would otherwise
+// often end up closest to a dim yellow.
+func quantizeColor(code uint8, n int) uint8 {
+	if int(code) < n {
+		return code
+	}
+	rgb := colorRGB(code)
+	gray := rgb[0] == rgb[1] && rgb[1] == rgb[2]
+	nearest, best := 0, -1
+	for i := 0; i < n; i++ {
+		if gray && !isGray(i) {
+			continue
+		}
+		distance := 0
+		for c := range rgb {
+			d := rgb[c] - basicPalette[i][c]
+			distance += d * d
+		}
+		if best < 0 || distance < best {
+			nearest, best = i, distance
+		}
+	}
+	return uint8(nearest)
+}
+
+// basicColorSGR returns the SGR parameters selecting one of the 16 basic
+// colors, keeping any attributes before the 38 or 48 of prefix
+func basicColorSGR(prefix string, code uint8) string {
+	base := 30
+	if strings.HasSuffix(prefix, "48") {
+		base = 40
+	}
+	if code >= 8 {
+		base += 60
+		code -= 8
+	}
+	return fmt.Sprintf("%s%d", strings.TrimSuffix(strings.TrimSuffix(prefix, "38"), "48"), base+int(code))
+}
+
+// terminalColors returns how many colors the prompt may use for the given
+// -color-mode. 0 stands for all 256 indices of the themes.
+func terminalColors(mode string) int {
+	switch mode {
+	case "8":
+		return 8
+	case "16":
+		return 16
+	case "256", "true":
+		return 0
+	}
+
+	colorTerm := os.Getenv("COLORTERM")
+	if colorTerm == "truecolor" || colorTerm == "24bit" {
+		return 0
+	}
+	term := os.Getenv("TERM")
+	if term == "" || term == "dumb" || strings.Contains(term, "256color") || strings.HasSuffix(term, "direct") {
+		return 0
+	}
+	// terminfo says 8 colors for plain xterm, but most terminals setting it
+	// support 256
+	if term == "xterm" {
+		return 0
+	}
+	out, err := exec.Command("tput", "colors").Output()
+	if err != nil {
+		return 0
+	}
+	colors, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	switch {
+	case err != nil || colors >= 256:
+		return 0
+	case colors >= 16:
+		return 16
+	case colors >= 8:
+		return 8
+	}
+	// terminals without any colors are better served by -mode and the theme
+	return 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+func Test_quantizeColor(t *testing.T) {
+	tests := []struct {
+		code uint8
+		n    int
+		want uint8
+	}{
+		{1, 16, 1},
+		{9, 16, 9},
+		{9, 8, 1},
+		{196, 16, 9}, // red
+		{196, 8, 1},
+		{21, 16, 4},   // blue
+		{208, 16, 3},  // orange
+		{231, 16, 15}, // white
+		{231, 8, 7},
+		{235, 16, 0}, // dark gray
+		{244, 16, 8}, // gray
+		{244, 8, 7},
+	}
+	for _, tt := range tests {
+		if got := quantizeColor(tt.code, tt.n); got != tt.want {
+			t.Errorf("quantizeColor(%d, %d) = %d, want %d", tt.code, tt.n, got, tt.want)
+		}
+	}
+}
+
+func Test_terminalColors(t *testing.T) {
+	for _, name := range []string{"TERM", "COLORTERM"} {
+		value, found := os.LookupEnv(name)
+		name := name
+		t.Cleanup(func() {
+			if found {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+	os.Setenv("TERM", "xterm-256color")
+	os.Unsetenv("COLORTERM")
+
+	tests := []struct {
+		mode string
+		want int
+	}{
+		{"8", 8},
+		{"16", 16},
+		{"256", 0},
+		{"true", 0},
+		{"auto", 0},
+	}
+	for _, tt := range tests {
+		if got := terminalColors(tt.mode); got != tt.want {
+			t.Errorf("terminalColors(%q) = %d, want %d", tt.mode, got, tt.want)
+		}
+	}
+
+	os.Setenv("TERM", "xterm")
+	if got := terminalColors("auto"); got != 0 {
+		t.Errorf("terminalColors(auto) with TERM=xterm = %d, want 0", got)
+	}
+}
+
+func Test_drawBasicColors(t *testing.T) {
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.Mode = "ascii"
+	cfg.Modules = []string{}
+	cfg.ColorMode = "16"
+	p := newPowerline(cfg, "/", alignLeft)
+	p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 231, Background: 196})
+
+	want := "\x1b[97m\x1b[101m a \x1b[0m\x1b[91m>\x1b[0m "
+	if got := p.draw(); got != want {
+		t.Errorf("draw() with 16 colors = %q, want %q", got, want)
+	}
+}
@@ -5,57 +5,156 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type SymbolMap map[string]SymbolTemplate
 type ShellMap map[string]ShellInfo
 type ThemeMap map[string]Theme
 type AliasMap map[string]string
+type PriorityMap map[string]int
+type WidthMap map[string]int
 
 type Config struct {
-	CwdMode                string    `json:"cwd-mode"`
-	CwdMaxDepth            int       `json:"cwd-max-depth"`
-	CwdMaxDirSize          int       `json:"cwd-max-dir-size"`
-	ColorizeHostname       bool      `json:"colorize-hostname"`
-	HostnameOnlyIfSSH      bool      `json:"hostname-only-if-ssh"`
-	SshAlternateIcon       bool      `json:"alternate-ssh-icon"`
-	EastAsianWidth         bool      `json:"east-asian-width"`
-	PromptOnNewLine        bool      `json:"newline"`
-	StaticPromptIndicator  bool      `json:"static-prompt-indicator"`
-	VenvNameSizeLimit      int       `json:"venv-name-size-limit"`
-	Jobs                   int       `json:"-"`
-	GitAssumeUnchangedSize int64     `json:"git-assume-unchanged-size"`
-	GitDisableStats        []string  `json:"git-disable-stats"`
-	GitMode                string    `json:"git-mode"`
-	Mode                   string    `json:"mode"`
-	Theme                  string    `json:"theme"`
-	Shell                  string    `json:"shell"`
-	Modules                []string  `json:"modules"`
-	ModulesRight           []string  `json:"modules-right"`
-	Priority               []string  `json:"priority"`
-	MaxWidthPercentage     int       `json:"max-width-percentage"`
-	TruncateSegmentWidth   int       `json:"truncate-segment-width"`
-	PrevError              int       `json:"-"`
-	NumericExitCodes       bool      `json:"numeric-exit-codes"`
-	IgnoreRepos            []string  `json:"ignore-repos"`
-	ShortenGKENames        bool      `json:"shorten-gke-names"`
-	ShortenEKSNames        bool      `json:"shorten-eks-names"`
-	ShortenOpenshiftNames  bool      `json:"shorten-openshift-names"`
-	ShellVar               string    `json:"shell-var"`
-	ShellVarNoWarnEmpty    bool      `json:"shell-var-no-warn-empty"`
-	TrimADDomain           bool      `json:"trim-ad-domain"`
-	PathAliases            AliasMap  `json:"path-aliases"`
-	Duration               string    `json:"-"`
-	DurationMin            string    `json:"duration-min"`
-	DurationLowPrecision   bool      `json:"duration-low-precision"`
-	Eval                   bool      `json:"eval"`
-	Condensed              bool      `json:"condensed"`
-	IgnoreWarnings         bool      `json:"ignore-warnings"`
-	Modes                  SymbolMap `json:"modes"`
-	Shells                 ShellMap  `json:"shells"`
-	Themes                 ThemeMap  `json:"themes"`
-	Time                   string    `json:"-"`
-	ViMode                 string    `json:"vi-mode"`
+	CwdMode                   string      `json:"cwd-mode"`
+	CwdMaxDepth               int         `json:"cwd-max-depth"`
+	CwdMaxDirSize             int         `json:"cwd-max-dir-size"`
+	ColorizeHostname          bool        `json:"colorize-hostname"`
+	HostnameOnlyIfSSH         bool        `json:"hostname-only-if-ssh"`
+	SshAlternateIcon          bool        `json:"alternate-ssh-icon"`
+	EastAsianWidth            bool        `json:"east-asian-width"`
+	PromptOnNewLine           bool        `json:"newline"`
+	StaticPromptIndicator     bool        `json:"static-prompt-indicator"`
+	VenvNameSizeLimit         int         `json:"venv-name-size-limit"`
+	Jobs                      int         `json:"-"`
+	GitAssumeUnchangedSize    int64       `json:"git-assume-unchanged-size"`
+	GitDisableStats           []string    `json:"git-disable-stats"`
+	GitMode                   string      `json:"git-mode"`
+	Mode                      string      `json:"mode"`
+	ModeSet                   bool        `json:"-"`
+	Theme                     string      `json:"theme"`
+	Shell                     string      `json:"shell"`
+	Modules                   []string    `json:"modules"`
+	ModulesRight              []string    `json:"modules-right"`
+	Priority                  []string    `json:"priority"`
+	SegmentPriorities         PriorityMap `json:"segment-priorities"`
+	SegmentMinWidths          WidthMap    `json:"segment-min-widths"`
+	MaxWidthPercentage        int         `json:"max-width-percentage"`
+	TruncateSegmentWidth      int         `json:"truncate-segment-width"`
+	PrevError                 int         `json:"-"`
+	StickyError               int         `json:"-"`
+	CommandNumber             int         `json:"-"`
+	NumericExitCodes          bool        `json:"numeric-exit-codes"`
+	IgnoreRepos               []string    `json:"ignore-repos"`
+	ShortenGKENames           bool        `json:"shorten-gke-names"`
+	ShortenEKSNames           bool        `json:"shorten-eks-names"`
+	ShortenOpenshiftNames     bool        `json:"shorten-openshift-names"`
+	ShellVar                  string      `json:"shell-var"`
+	ShellVarNoWarnEmpty       bool        `json:"shell-var-no-warn-empty"`
+	TrimADDomain              bool        `json:"trim-ad-domain"`
+	PathAliases               AliasMap    `json:"path-aliases"`
+	Duration                  string      `json:"-"`
+	DurationMin               string      `json:"duration-min"`
+	DurationLowPrecision      bool        `json:"duration-low-precision"`
+	Eval                      bool        `json:"eval"`
+	Condensed                 bool        `json:"condensed"`
+	IgnoreWarnings            bool        `json:"ignore-warnings"`
+	Modes                     SymbolMap   `json:"modes"`
+	Shells                    ShellMap    `json:"shells"`
+	Themes                    ThemeMap    `json:"themes"`
+	Time                      string      `json:"-"`
+	ViMode                    string      `json:"vi-mode"`
+	GitSyncGlyphMode          bool        `json:"git-sync-glyph-mode"`
+	GitUntrackedDirAsSingle   bool        `json:"git-untracked-dir-as-single"`
+	GitShowIgnoredCount       bool        `json:"git-show-ignored-count"`
+	AsdfTools                 []string    `json:"asdf-tools"`
+	TemplateSegment           string      `json:"template-segment"`
+	GitDetachedFormat         string      `json:"git-detached-format"`
+	GitHideInHome             bool        `json:"git-hide-in-home"`
+	GitDetachedAheadBehind    bool        `json:"git-detached-ahead-behind"`
+	GitHideWhenClean          bool        `json:"git-hide-when-clean"`
+	Output                    string      `json:"output"`
+	GitShowSubmoduleCount     bool        `json:"git-show-submodule-count"`
+	NerdFontVersion           int         `json:"nerd-font-version"`
+	GitShowPushAheadBehind    bool        `json:"git-show-push-ahead-behind"`
+	Debug                     bool        `json:"debug"`
+	TitleFormat               string      `json:"title-format"`
+	GitStatsThinSeparator     bool        `json:"git-stats-thin-separator"`
+	GitShowRepoType           bool        `json:"git-show-repo-type"`
+	GitStashFileCount         bool        `json:"git-stash-file-count"`
+	GitShowGerrit             bool        `json:"git-show-gerrit"`
+	EvalSafe                  bool        `json:"eval-safe"`
+	GitBranchBasenameOnly     bool        `json:"git-branch-basename-only"`
+	GitAheadBehindCap         int         `json:"git-ahead-behind-cap"`
+	GitFSMonitor              bool        `json:"git-fsmonitor"`
+	GitShowRemoteName         bool        `json:"git-show-remote-name"`
+	KubeShowHelm              bool        `json:"kube-show-helm"`
+	GitStatsSharedBg          int         `json:"git-stats-shared-bg"`
+	VaultCheckToken           bool        `json:"vault-check-token"`
+	ExitSticky                bool        `json:"exit-sticky"`
+	GitDivergedGlyph          bool        `json:"git-diverged-glyph"`
+	CwdGradient               bool        `json:"cwd-gradient"`
+	GitShowBehindDefault      bool        `json:"git-show-behind-default"`
+	Reverse                   bool        `json:"reverse"`
+	GitPushTargetBadge        bool        `json:"git-push-target-badge"`
+	GitCLIFallbackOnError     bool        `json:"git-cli-fallback-on-error"`
+	GitStashClassify          bool        `json:"git-stash-classify"`
+	BuildStatusFile           string      `json:"build-status-file"`
+	UserhostFormat            string      `json:"userhost-format"`
+	DefaultUser               string      `json:"default-user"`
+	HideDefaultUser           bool        `json:"hide-default-user"`
+	GitShowCommitAge          bool        `json:"git-show-commit-age"`
+	GitAgeDate                string      `json:"git-age-date"`
+	GitShowUnpushedIndicator  bool        `json:"git-show-unpushed-indicator"`
+	GitConflictProminent      bool        `json:"git-conflict-prominent"`
+	CwdIgnoreErrors           bool        `json:"cwd-ignore-errors"`
+	CwdNativeToken            bool        `json:"cwd-native-token"`
+	PythonPrefer              string      `json:"python-prefer"`
+	GitRecurseSubmodules      bool        `json:"git-recurse-submodules"`
+	GitBranchColorBySync      bool        `json:"git-branch-color-by-sync"`
+	CwdHeadCount              int         `json:"cwd-head-count"`
+	CwdTailCount              int         `json:"cwd-tail-count"`
+	GitUntrackedNotDirty      bool        `json:"git-untracked-not-dirty"`
+	GitShowGCHint             bool        `json:"git-show-gc-hint"`
+	GitGCHintThreshold        int         `json:"git-gc-hint-threshold"`
+	GCPShowAccount            bool        `json:"gcp-show-account"`
+	GCPConfigFilesOnly        bool        `json:"gcp-config-files-only"`
+	GitShowNoUpstream         bool        `json:"git-show-no-upstream"`
+	GitStatsBeforeBranch      bool        `json:"git-stats-before-branch"`
+	GitSymbolBranch           string      `json:"git-symbol-branch"`
+	GitSymbolDetached         string      `json:"git-symbol-detached"`
+	GitSymbolAhead            string      `json:"git-symbol-ahead"`
+	GitSymbolBehind           string      `json:"git-symbol-behind"`
+	GitSymbolStaged           string      `json:"git-symbol-staged"`
+	GitSymbolNotStaged        string      `json:"git-symbol-not-staged"`
+	GitSymbolUntracked        string      `json:"git-symbol-untracked"`
+	GitSymbolConflicted       string      `json:"git-symbol-conflicted"`
+	GitSymbolStashed          string      `json:"git-symbol-stashed"`
+	Side                      string      `json:"side"`
+	GithubCacheFile           string      `json:"github-cache-file"`
+	GithubCacheMaxAge         int         `json:"github-cache-max-age"`
+	ColorMode                 string      `json:"color-mode"`
+	DirEnteredAt              string      `json:"-"`
+	GitShowMergeBase          bool        `json:"git-show-merge-base"`
+	GitMergeBaseRef           string      `json:"git-merge-base-ref"`
+	GitShowNested             bool        `json:"git-show-nested"`
+	FillToWidth               bool        `json:"fill-to-width"`
+	FillChar                  string      `json:"fill-char"`
+	MultilineContinuation     string      `json:"multiline-continuation"`
+	LastCommand               string      `json:"-"`
+	LastCommandMaxLength      int         `json:"last-command-max-length"`
+	GitSkipOnFS               []string    `json:"git-skip-on-fs"`
+	GitDetectLock             bool        `json:"git-detect-lock"`
+	MuxStates                 []string    `json:"mux-states"`
+	Group                     []string    `json:"group"`
+	GitSubmodulePointers      bool        `json:"git-submodule-pointers"`
+	CwdMaxLength              int         `json:"cwd-max-length"`
+	GitShowCommitSubject      bool        `json:"git-show-commit-subject"`
+	GitCommitSubjectMaxLength int         `json:"git-commit-subject-max-length"`
+	PostHook                  string      `json:"post-hook"`
+	GitUpstreamFallback       bool        `json:"git-upstream-fallback"`
+	GitShowRemoteCount        bool        `json:"git-show-remote-count"`
+	GitLiteStatus             bool        `json:"git-lite-status"`
 }
 
 func (mode *SymbolTemplate) UnmarshalJSON(data []byte) error {
@@ -68,14 +167,36 @@ func (mode *SymbolTemplate) UnmarshalJSON(data []byte) error {
 	return err
 }
 
+// UnmarshalJSON reads a theme on top of the default theme. Themes setting
+// FallbackFg or FallbackBg use those for all the colors they leave out instead.
 func (theme *Theme) UnmarshalJSON(data []byte) error {
 	type Alias Theme
 	tmp := defaults.Themes[defaults.Theme]
 	err := json.Unmarshal(data, (*Alias)(&tmp))
-	if err == nil {
-		*theme = tmp
+	if err != nil {
+		return err
 	}
-	return err
+
+	keys := map[string]json.RawMessage{}
+	json.Unmarshal(data, &keys)
+	isSet := func(field string) bool {
+		for key := range keys {
+			if strings.EqualFold(key, field) {
+				return true
+			}
+		}
+		return false
+	}
+	var fallback struct {
+		FallbackFg *uint8
+		FallbackBg *uint8
+	}
+	json.Unmarshal(data, &fallback)
+	if fallback.FallbackFg != nil || fallback.FallbackBg != nil {
+		tmp = tmp.withFallbackColors(isSet, fallback.FallbackFg, fallback.FallbackBg)
+	}
+	*theme = tmp
+	return nil
 }
 
 func configPath() string {
@@ -83,13 +204,76 @@ func configPath() string {
 	return filepath.Join(home, ".config", "powerline-go", "config.json")
 }
 
-func (cfg *Config) Load() error {
-	path := configPath()
-	file, err := ioutil.ReadFile(path)
+// configFiles collects the paths given via repeated -config flags
+type configFiles []string
+
+func (files *configFiles) String() string {
+	return strings.Join(*files, ",")
+}
+
+func (files *configFiles) Set(value string) error {
+	for _, path := range strings.Split(value, ",") {
+		if path != "" {
+			*files = append(*files, path)
+		}
+	}
+	return nil
+}
+
+// listMergeKey names the per-file setting that selects, for each top-level
+// key holding a list, whether it is appended to or replaces earlier files.
+const listMergeKey = "list-merge"
+
+func mergeConfig(base, layer map[string]interface{}, strategies map[string]interface{}) {
+	for key, value := range layer {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if existing, ok := base[key].(map[string]interface{}); ok {
+				mergeConfig(existing, v, nil)
+				continue
+			}
+		case []interface{}:
+			if existing, ok := base[key].([]interface{}); ok && strategies[key] == "append" {
+				base[key] = append(existing, v...)
+				continue
+			}
+		}
+		base[key] = value
+	}
+}
+
+// Load reads the given config files, or the default config file if none are
+// given. Later files are deep-merged over earlier ones.
+func (cfg *Config) Load(paths ...string) error {
+	explicit := len(paths) > 0
+	if !explicit {
+		paths = []string{configPath()}
+	}
+
+	merged := map[string]interface{}{}
+	for _, path := range paths {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			if explicit {
+				return err
+			}
+			return nil // fail silently
+		}
+		layer := map[string]interface{}{}
+		err = json.Unmarshal(file, &layer)
+		if err != nil {
+			return err
+		}
+		strategies, _ := layer[listMergeKey].(map[string]interface{})
+		delete(layer, listMergeKey)
+		mergeConfig(merged, layer, strategies)
+	}
+
+	data, err := json.Marshal(merged)
 	if err != nil {
-		return nil // fail silently
+		return err
 	}
-	return json.Unmarshal(file, cfg)
+	return json.Unmarshal(data, cfg)
 }
 
 func (cfg *Config) Save() error {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func Test_loadLayeredConfig(t *testing.T) {
+	dir := t.TempDir()
+	team := filepath.Join(dir, "team.json")
+	personal := filepath.Join(dir, "personal.json")
+	ioutil.WriteFile(team, []byte(`{
+		"modules": ["cwd", "git"],
+		"themes": {"team": {"RepoCleanBg": 22, "RepoDirtyBg": 88}}
+	}`), 0644)
+	ioutil.WriteFile(personal, []byte(`{
+		"list-merge": {"modules": "append"},
+		"modules": ["exit"],
+		"themes": {"team": {"RepoDirtyBg": 161}}
+	}`), 0644)
+
+	cfg := defaults
+	cfg.Themes = ThemeMap{}
+	if err := cfg.Load(team, personal); err != nil {
+		t.Fatal(err)
+	}
+
+	theme := cfg.Themes["team"]
+	if theme.RepoCleanBg != 22 {
+		t.Errorf("RepoCleanBg = %d, want 22 from team config", theme.RepoCleanBg)
+	}
+	if theme.RepoDirtyBg != 161 {
+		t.Errorf("RepoDirtyBg = %d, want 161 from personal config", theme.RepoDirtyBg)
+	}
+	if len(cfg.Modules) != 3 || cfg.Modules[2] != "exit" {
+		t.Errorf("Modules = %v, want [cwd git exit]", cfg.Modules)
+	}
+}
+
+func Test_themeFallbackColors(t *testing.T) {
+	var theme Theme
+	if err := json.Unmarshal([]byte(`{"FallbackFg": 0, "FallbackBg": 22, "RepoDirtyBg": 161}`), &theme); err != nil {
+		t.Fatal(err)
+	}
+	if theme.AzureFg != 0 || theme.AzureBg != 22 {
+		t.Errorf("unset Azure colors = %d on %d, want 0 on 22", theme.AzureFg, theme.AzureBg)
+	}
+	if theme.DefaultFg != 0 || theme.DefaultBg != 22 {
+		t.Errorf("unset default colors = %d on %d, want 0 on 22", theme.DefaultFg, theme.DefaultBg)
+	}
+	if theme.RepoDirtyBg != 161 {
+		t.Errorf("RepoDirtyBg = %d, want 161 as set", theme.RepoDirtyBg)
+	}
+	if want := defaults.Themes[defaults.Theme].Reset; theme.Reset != want {
+		t.Errorf("Reset = %d, want %d from the default theme", theme.Reset, want)
+	}
+
+	// only the fallback that is given is used
+	if err := json.Unmarshal([]byte(`{"FallbackBg": 22}`), &theme); err != nil {
+		t.Fatal(err)
+	}
+	if want := defaults.Themes[defaults.Theme].AzureFg; theme.AzureFg != want || theme.AzureBg != 22 {
+		t.Errorf("unset Azure colors = %d on %d, want %d on 22", theme.AzureFg, theme.AzureBg, want)
+	}
+
+	// DefaultFg and DefaultBg keep their meaning of the colors of segments
+	// without their own, unset colors come from the default theme
+	if err := json.Unmarshal([]byte(`{"DefaultFg": 0, "DefaultBg": 22, "RepoDirtyBg": 161}`), &theme); err != nil {
+		t.Fatal(err)
+	}
+	if want := defaults.Themes[defaults.Theme].AzureBg; theme.AzureBg != want || theme.DefaultBg != 22 {
+		t.Errorf("AzureBg = %d, DefaultBg = %d, want %d from the default theme and 22", theme.AzureBg, theme.DefaultBg, want)
+	}
+}
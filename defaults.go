@@ -17,6 +17,7 @@ var defaults = Config{
 	GitDisableStats:        []string{},
 	GitMode:                "fancy",
 	Mode:                   "patched",
+	ModeSet:                false,
 	Theme:                  "default",
 	Shell:                  "autodetect",
 	Modules: []string{
@@ -47,9 +48,13 @@ var defaults = Config{
 		"exit",
 		"cwd-path",
 	},
+	SegmentPriorities:    PriorityMap{},
+	SegmentMinWidths:     WidthMap{},
 	MaxWidthPercentage:   0,
 	TruncateSegmentWidth: 16,
 	PrevError:            0,
+	StickyError:          0,
+	CommandNumber:        0,
 	NumericExitCodes:     false,
 	IgnoreRepos:          []string{},
 	ShortenGKENames:      false,
@@ -86,6 +91,77 @@ var defaults = Config{
 			VenvIndicator: "\uE235",
 			NodeIndicator: "\u2B22",
 			RvmIndicator:  "\uE92B",
+
+			RepoSyncAhead:    "\u21E1",
+			RepoSyncBehind:   "\u21E3",
+			RepoSyncDiverged: "\u21D5",
+			RepoSynced:       "\u2713",
+
+			RepoIgnored: "I",
+
+			RepoSubmodules: "\u24C8",
+
+			RepoPushAhead:  "\u21A5",
+			RepoPushBehind: "\u21A7",
+
+			RepoTypeBare:      "\u25CC",
+			RepoTypeSubmodule: "\u29C9",
+			RepoTypeWorktree:  "\u2387",
+			RepoTypeSparse:    "\u2591",
+
+			SSHAgent: "\U0001F511",
+
+			RepoRemote: "\u2601",
+
+			RepoEmpty: "\u2205",
+
+			RepoInGitDir: "\u2699",
+
+			Todo: "\U0001F4DD",
+
+			RepoBehindDefault: "\u21A1",
+
+			Editor: "\u270E",
+
+			RepoPushTarget: "\u2192",
+
+			RepoStashNamed: "\u270E",
+
+			BuildOk:     "\u2714",
+			BuildFailed: "\u2718",
+
+			RepoUnpushed: "\u26A0",
+
+			PyenvIndicator: "py",
+
+			RepoRecursive: "\u21BB",
+
+			RepoGCNeeded: "\u267B",
+
+			Azure: "\u2601",
+
+			RepoLocal: "\u2302",
+
+			GithubPullRequests: "\u2442",
+
+			RepoChanges: "\u00B1",
+
+			RepoSymbolicRef: "\u21AA",
+
+			LastCommand: "\u23CE",
+
+			RepoDot: "\u25CF",
+
+			RepoIndexLocked: "\u231B",
+
+			MuxZoomed:   "\u26F6",
+			MuxCopyMode: "\u2398",
+
+			Container: "\u2B22",
+
+			RepoRemotes: "\u21c4",
+
+			RepoBisect: "BISECT",
 		},
 		"patched": {
 			Lock:                 "\uE0A2",
@@ -109,6 +185,77 @@ var defaults = Config{
 			VenvIndicator: "\uE235",
 			NodeIndicator: "\u2B22",
 			RvmIndicator:  "\uE92B",
+
+			RepoSyncAhead:    "\u21E1",
+			RepoSyncBehind:   "\u21E3",
+			RepoSyncDiverged: "\u21D5",
+			RepoSynced:       "\u2713",
+
+			RepoIgnored: "I",
+
+			RepoSubmodules: "\u24C8",
+
+			RepoPushAhead:  "\u21A5",
+			RepoPushBehind: "\u21A7",
+
+			RepoTypeBare:      "\u25CC",
+			RepoTypeSubmodule: "\u29C9",
+			RepoTypeWorktree:  "\u2387",
+			RepoTypeSparse:    "\u2591",
+
+			SSHAgent: "\U0001F511",
+
+			RepoRemote: "\u2601",
+
+			RepoEmpty: "\u2205",
+
+			RepoInGitDir: "\u2699",
+
+			Todo: "\U0001F4DD",
+
+			RepoBehindDefault: "\u21A1",
+
+			Editor: "\u270E",
+
+			RepoPushTarget: "\u2192",
+
+			RepoStashNamed: "\u270E",
+
+			BuildOk:     "\u2714",
+			BuildFailed: "\u2718",
+
+			RepoUnpushed: "\u26A0",
+
+			PyenvIndicator: "py",
+
+			RepoRecursive: "\u21BB",
+
+			RepoGCNeeded: "\u267B",
+
+			Azure: "\u2601",
+
+			RepoLocal: "\u2302",
+
+			GithubPullRequests: "\u2442",
+
+			RepoChanges: "\u00B1",
+
+			RepoSymbolicRef: "\u21AA",
+
+			LastCommand: "\u23CE",
+
+			RepoDot: "\u25CF",
+
+			RepoIndexLocked: "\u231B",
+
+			MuxZoomed:   "\u26F6",
+			MuxCopyMode: "\u2398",
+
+			Container: "\u2B22",
+
+			RepoRemotes: "\u21c4",
+
+			RepoBisect: "BISECT",
 		},
 		"flat": {
 			RepoDetached:   "\u2693",
@@ -123,6 +270,361 @@ var defaults = Config{
 			VenvIndicator: "\uE235",
 			NodeIndicator: "\u2B22",
 			RvmIndicator:  "\uE92B",
+
+			RepoSyncAhead:    "\u21E1",
+			RepoSyncBehind:   "\u21E3",
+			RepoSyncDiverged: "\u21D5",
+			RepoSynced:       "\u2713",
+
+			RepoIgnored: "I",
+
+			RepoSubmodules: "\u24C8",
+
+			RepoPushAhead:  "\u21A5",
+			RepoPushBehind: "\u21A7",
+
+			RepoTypeBare:      "\u25CC",
+			RepoTypeSubmodule: "\u29C9",
+			RepoTypeWorktree:  "\u2387",
+			RepoTypeSparse:    "\u2591",
+
+			SSHAgent: "\U0001F511",
+
+			RepoRemote: "\u2601",
+
+			RepoEmpty: "\u2205",
+
+			RepoInGitDir: "\u2699",
+
+			Todo: "\U0001F4DD",
+
+			RepoBehindDefault: "\u21A1",
+
+			Editor: "\u270E",
+
+			RepoPushTarget: "\u2192",
+
+			RepoStashNamed: "\u270E",
+
+			BuildOk:     "\u2714",
+			BuildFailed: "\u2718",
+
+			RepoUnpushed: "\u26A0",
+
+			PyenvIndicator: "py",
+
+			RepoRecursive: "\u21BB",
+
+			RepoGCNeeded: "\u267B",
+
+			Azure: "\u2601",
+
+			RepoLocal: "\u2302",
+
+			GithubPullRequests: "\u2442",
+
+			RepoChanges: "\u00B1",
+
+			RepoSymbolicRef: "\u21AA",
+
+			LastCommand: "\u23CE",
+
+			RepoDot: "\u25CF",
+
+			RepoIndexLocked: "\u231B",
+
+			MuxZoomed:   "\u26F6",
+			MuxCopyMode: "\u2398",
+
+			Container: "\u2B22",
+
+			RepoRemotes: "\u21c4",
+
+			RepoBisect: "BISECT",
+		},
+		"ascii": {
+			Lock:                 "RO",
+			Network:              "SSH",
+			NetworkAlternate:     "SSH",
+			Separator:            ">",
+			SeparatorThin:        ">",
+			SeparatorReverse:     "<",
+			SeparatorReverseThin: "<",
+
+			RepoBranch:     "branch",
+			RepoDetached:   "@",
+			RepoAhead:      "^",
+			RepoBehind:     "v",
+			RepoStaged:     "S",
+			RepoNotStaged:  "M",
+			RepoUntracked:  "?",
+			RepoConflicted: "!",
+			RepoStashed:    "*",
+
+			VenvIndicator: "py",
+			NodeIndicator: "node",
+			RvmIndicator:  "rb",
+
+			RepoSyncAhead:    "^",
+			RepoSyncBehind:   "v",
+			RepoSyncDiverged: "^v",
+			RepoSynced:       "=",
+
+			RepoIgnored: "I",
+
+			RepoSubmodules: "sub",
+
+			RepoPushAhead:  "p^",
+			RepoPushBehind: "pv",
+
+			RepoTypeBare:      "bare",
+			RepoTypeSubmodule: "sub",
+			RepoTypeWorktree:  "wt",
+			RepoTypeSparse:    "sparse",
+
+			SSHAgent: "keys",
+
+			RepoRemote: "r:",
+
+			RepoEmpty: "empty",
+
+			RepoInGitDir: "gitdir",
+
+			Todo: "TODO",
+
+			RepoBehindDefault: "vd",
+
+			Editor: "ed",
+
+			RepoPushTarget: "->",
+
+			RepoStashNamed: "~",
+
+			BuildOk:     "ok",
+			BuildFailed: "fail",
+
+			RepoUnpushed: "!",
+
+			PyenvIndicator: "py",
+
+			RepoRecursive: "(r)",
+
+			RepoGCNeeded: "gc",
+
+			Azure: "az",
+
+			RepoLocal: "local",
+
+			GithubPullRequests: "PR",
+
+			RepoChanges: "*",
+
+			RepoSymbolicRef: "->",
+
+			LastCommand: "$",
+
+			RepoDot: "o",
+
+			RepoIndexLocked: "lock",
+
+			MuxZoomed:   "Z",
+			MuxCopyMode: "COPY",
+
+			Container: "ctr",
+
+			RepoRemotes: "<>",
+
+			RepoBisect: "BISECT",
+		},
+		// Nerd Fonts 3 moved the Material Design icons out of the range used by
+		// Nerd Fonts 2, so both need their own set of glyphs
+		"nerd-font-v2": {
+			Lock:                 "\uF83D",
+			Network:              "\uF816",
+			NetworkAlternate:     "\uFC28",
+			Separator:            "\uE0B0",
+			SeparatorThin:        "\uE0B1",
+			SeparatorReverse:     "\uE0B2",
+			SeparatorReverseThin: "\uE0B3",
+
+			RepoBranch:     "\uFB2B",
+			RepoDetached:   "\uFC17",
+			RepoAhead:      "\uF55C",
+			RepoBehind:     "\uF544",
+			RepoStaged:     "\uF62B",
+			RepoNotStaged:  "\uF8EA",
+			RepoUntracked:  "\uF914",
+			RepoConflicted: "\uF525",
+			RepoStashed:    "\uF53B",
+
+			VenvIndicator: "\uF81F",
+			NodeIndicator: "\uF898",
+			RvmIndicator:  "\uE739",
+
+			RepoSyncAhead:    "\u21E1",
+			RepoSyncBehind:   "\u21E3",
+			RepoSyncDiverged: "\u21D5",
+			RepoSynced:       "\u2713",
+
+			RepoIgnored: "I",
+
+			RepoSubmodules: "\u24C8",
+
+			RepoPushAhead:  "\u21A5",
+			RepoPushBehind: "\u21A7",
+
+			RepoTypeBare:      "\u25CC",
+			RepoTypeSubmodule: "\u29C9",
+			RepoTypeWorktree:  "\u2387",
+			RepoTypeSparse:    "\u2591",
+
+			SSHAgent: "\U0001F511",
+
+			RepoRemote: "\u2601",
+
+			RepoEmpty: "\u2205",
+
+			RepoInGitDir: "\u2699",
+
+			Todo: "\U0001F4DD",
+
+			RepoBehindDefault: "\u21A1",
+
+			Editor: "\u270E",
+
+			RepoPushTarget: "\u2192",
+
+			RepoStashNamed: "\u270E",
+
+			BuildOk:     "\u2714",
+			BuildFailed: "\u2718",
+
+			RepoUnpushed: "\u26A0",
+
+			PyenvIndicator: "\uE73C",
+
+			RepoRecursive: "\u21BB",
+
+			RepoGCNeeded: "\u267B",
+
+			Azure: "\uFD03",
+
+			RepoLocal: "\u2302",
+
+			GithubPullRequests: "\uF407",
+
+			RepoChanges: "\u00B1",
+
+			RepoSymbolicRef: "\u21AA",
+
+			LastCommand: "\u23CE",
+
+			RepoDot: "\u25CF",
+
+			RepoIndexLocked: "\u231B",
+
+			MuxZoomed:   "\u26F6",
+			MuxCopyMode: "\u2398",
+
+			Container: "\u2B22",
+
+			RepoRemotes: "\u21c4",
+
+			RepoBisect: "BISECT",
+		},
+		"nerd-font-v3": {
+			Lock:                 "\U000F033E",
+			Network:              "\U000F0317",
+			NetworkAlternate:     "\U000F0729",
+			Separator:            "\uE0B0",
+			SeparatorThin:        "\uE0B1",
+			SeparatorReverse:     "\uE0B2",
+			SeparatorReverseThin: "\uE0B3",
+
+			RepoBranch:     "\U000F062C",
+			RepoDetached:   "\U000F0718",
+			RepoAhead:      "\U000F005D",
+			RepoBehind:     "\U000F0045",
+			RepoStaged:     "\U000F012C",
+			RepoNotStaged:  "\U000F03EB",
+			RepoUntracked:  "\U000F0415",
+			RepoConflicted: "\U000F0026",
+			RepoStashed:    "\U000F003C",
+
+			VenvIndicator: "\U000F0320",
+			NodeIndicator: "\U000F0399",
+			RvmIndicator:  "\uE739",
+
+			RepoSyncAhead:    "\u21E1",
+			RepoSyncBehind:   "\u21E3",
+			RepoSyncDiverged: "\u21D5",
+			RepoSynced:       "\u2713",
+
+			RepoIgnored: "I",
+
+			RepoSubmodules: "\u24C8",
+
+			RepoPushAhead:  "\u21A5",
+			RepoPushBehind: "\u21A7",
+
+			RepoTypeBare:      "\u25CC",
+			RepoTypeSubmodule: "\u29C9",
+			RepoTypeWorktree:  "\u2387",
+			RepoTypeSparse:    "\u2591",
+
+			SSHAgent: "\U0001F511",
+
+			RepoRemote: "\u2601",
+
+			RepoEmpty: "\u2205",
+
+			RepoInGitDir: "\u2699",
+
+			Todo: "\U0001F4DD",
+
+			RepoBehindDefault: "\u21A1",
+
+			Editor: "\u270E",
+
+			RepoPushTarget: "\u2192",
+
+			RepoStashNamed: "\u270E",
+
+			BuildOk:     "\u2714",
+			BuildFailed: "\u2718",
+
+			RepoUnpushed: "\u26A0",
+
+			PyenvIndicator: "\uE73C",
+
+			RepoRecursive: "\u21BB",
+
+			RepoGCNeeded: "\u267B",
+
+			Azure: "\U000F0805",
+
+			RepoLocal: "\u2302",
+
+			GithubPullRequests: "\uF407",
+
+			RepoChanges: "\u00B1",
+
+			RepoSymbolicRef: "\u21AA",
+
+			LastCommand: "\u23CE",
+
+			RepoDot: "\u25CF",
+
+			RepoIndexLocked: "\u231B",
+
+			MuxZoomed:   "\u26F6",
+			MuxCopyMode: "\u2398",
+
+			Container: "\u2B22",
+
+			RepoRemotes: "\u21c4",
+
+			RepoBisect: "BISECT",
 		},
 	},
 	Shells: ShellMap{
@@ -134,6 +636,8 @@ var defaults = Config{
 			EscapedDollar:    `\$`,
 			EvalPromptPrefix: `PS1="`,
 			EvalPromptSuffix: `"`,
+			TitleTemplate:    `\[\e]0;%s\e\\\\\]`,
+			CwdToken:         `\w`,
 		},
 		"zsh": {
 			ColorTemplate:         "%%{\u001b%s%%}",
@@ -141,17 +645,21 @@ var defaults = Config{
 			EscapedBackslash:      `\\`,
 			EscapedBacktick:       "\\`",
 			EscapedDollar:         `\$`,
+			EscapedPercent:        `%%`,
 			EvalPromptPrefix:      `PROMPT="`,
 			EvalPromptSuffix:      `"`,
 			EvalPromptRightPrefix: `RPROMPT="`,
 			EvalPromptRightSuffix: `"`,
+			TitleTemplate:         "%%{\u001b]0;%s\u001b\\\\%%}",
+			CwdToken:              "%~",
 		},
 		"bare": {
-			ColorTemplate: "%s",
+			ColorTemplate:    "%s",
 			RootIndicator:    "$",
 			EscapedBackslash: `\`,
 			EscapedBacktick:  "`",
 			EscapedDollar:    `$`,
+			TitleTemplate:    "\u001b]0;%s\u001b\\",
 		},
 	},
 	Themes: ThemeMap{
@@ -545,6 +1053,108 @@ var defaults = Config{
 			ViModeCommandBg: 250,
 			ViModeInsertFg:  22,
 			ViModeInsertBg:  70,
+
+			GitSyncAheadFg:    15,
+			GitSyncAheadBg:    31,
+			GitSyncBehindFg:   15,
+			GitSyncBehindBg:   130,
+			GitSyncDivergedFg: 15,
+			GitSyncDivergedBg: 161,
+			GitSyncedFg:       15,
+			GitSyncedBg:       22,
+
+			GitIgnoredFg: 244,
+			GitIgnoredBg: 236,
+
+			AsdfFg: 15,
+			AsdfBg: 96,
+
+			GitSubmodulesFg: 250,
+			GitSubmodulesBg: 238,
+
+			GitPushAheadFg:  250,
+			GitPushAheadBg:  240,
+			GitPushBehindFg: 250,
+			GitPushBehindBg: 240,
+
+			SSHAgentFg:      254,
+			SSHAgentBg:      166,
+			SSHAgentEmptyFg: 15,
+			SSHAgentEmptyBg: 161,
+
+			GitRemoteFg: 250,
+			GitRemoteBg: 238,
+
+			VaultFg:        15,
+			VaultBg:        24,
+			VaultInvalidFg: 15,
+			VaultInvalidBg: 161,
+
+			CmdStickyFg: 250,
+			CmdStickyBg: 52,
+
+			CwdGradientStartFg: 243,
+			CwdGradientEndFg:   252,
+
+			TodoFg: 250,
+			TodoBg: 238,
+
+			GitBehindDefaultFg: 250,
+			GitBehindDefaultBg: 240,
+
+			EditorFg: 15,
+			EditorBg: 24,
+
+			GitPushTargetFg: 250,
+			GitPushTargetBg: 238,
+
+			GitAgeFg: 250,
+			GitAgeBg: 238,
+
+			GitUnpushedFg: 15,
+			GitUnpushedBg: 161,
+
+			GitConflictProminentFg: 226,
+			GitConflictProminentBg: 160,
+
+			PyenvFg: 220,
+			PyenvBg: 25,
+
+			GitGCHintFg: 15,
+			GitGCHintBg: 130,
+
+			AzureFg: 15,
+			AzureBg: 32,
+
+			GithubFg: 15,
+			GithubBg: 237,
+
+			GitMergeBaseFg: 250,
+			GitMergeBaseBg: 238,
+
+			GitNestedFg: 250,
+			GitNestedBg: 240,
+
+			FillFg: 240,
+			FillBg: 236,
+
+			LastCommandFg: 250,
+			LastCommandBg: 238,
+
+			MuxStateFg: 16,
+			MuxStateBg: 178,
+
+			ContainerFg: 15,
+			ContainerBg: 91,
+
+			GitCommitSubjectFg: 250,
+			GitCommitSubjectBg: 238,
+
+			GitOperationFg: 15,
+			GitOperationBg: 166,
+
+			GitRemoteCountFg: 250,
+			GitRemoteCountBg: 238,
 		},
 		"low-contrast": {
 			Reset: 0xFF,
@@ -913,6 +1523,108 @@ var defaults = Config{
 			ViModeCommandBg: 250,
 			ViModeInsertFg:  22,
 			ViModeInsertBg:  70,
+
+			GitSyncAheadFg:    31,
+			GitSyncAheadBg:    15,
+			GitSyncBehindFg:   130,
+			GitSyncBehindBg:   15,
+			GitSyncDivergedFg: 161,
+			GitSyncDivergedBg: 15,
+			GitSyncedFg:       22,
+			GitSyncedBg:       15,
+
+			GitIgnoredFg: 244,
+			GitIgnoredBg: 254,
+
+			AsdfFg: 96,
+			AsdfBg: 15,
+
+			GitSubmodulesFg: 238,
+			GitSubmodulesBg: 252,
+
+			GitPushAheadFg:  240,
+			GitPushAheadBg:  250,
+			GitPushBehindFg: 240,
+			GitPushBehindBg: 251,
+
+			SSHAgentFg:      166,
+			SSHAgentBg:      254,
+			SSHAgentEmptyFg: 254,
+			SSHAgentEmptyBg: 124,
+
+			GitRemoteFg: 238,
+			GitRemoteBg: 252,
+
+			VaultFg:        24,
+			VaultBg:        254,
+			VaultInvalidFg: 254,
+			VaultInvalidBg: 124,
+
+			CmdStickyFg: 124,
+			CmdStickyBg: 252,
+
+			CwdGradientStartFg: 246,
+			CwdGradientEndFg:   237,
+
+			TodoFg: 238,
+			TodoBg: 252,
+
+			GitBehindDefaultFg: 240,
+			GitBehindDefaultBg: 251,
+
+			EditorFg: 24,
+			EditorBg: 254,
+
+			GitPushTargetFg: 238,
+			GitPushTargetBg: 252,
+
+			GitAgeFg: 238,
+			GitAgeBg: 252,
+
+			GitUnpushedFg: 15,
+			GitUnpushedBg: 161,
+
+			GitConflictProminentFg: 15,
+			GitConflictProminentBg: 160,
+
+			PyenvFg: 25,
+			PyenvBg: 254,
+
+			GitGCHintFg: 130,
+			GitGCHintBg: 252,
+
+			AzureFg: 32,
+			AzureBg: 254,
+
+			GithubFg: 237,
+			GithubBg: 254,
+
+			GitMergeBaseFg: 240,
+			GitMergeBaseBg: 252,
+
+			GitNestedFg: 240,
+			GitNestedBg: 251,
+
+			FillFg: 245,
+			FillBg: 254,
+
+			LastCommandFg: 238,
+			LastCommandBg: 252,
+
+			MuxStateFg: 16,
+			MuxStateBg: 222,
+
+			ContainerFg: 91,
+			ContainerBg: 254,
+
+			GitCommitSubjectFg: 240,
+			GitCommitSubjectBg: 252,
+
+			GitOperationFg: 15,
+			GitOperationBg: 130,
+
+			GitRemoteCountFg: 238,
+			GitRemoteCountBg: 252,
 		},
 		"solarized-dark16": {
 			Reset:              8,
@@ -1254,6 +1966,108 @@ var defaults = Config{
 			ViModeCommandBg: 250,
 			ViModeInsertFg:  22,
 			ViModeInsertBg:  70,
+
+			GitSyncAheadFg:    15,
+			GitSyncAheadBg:    4,
+			GitSyncBehindFg:   15,
+			GitSyncBehindBg:   9,
+			GitSyncDivergedFg: 15,
+			GitSyncDivergedBg: 1,
+			GitSyncedFg:       15,
+			GitSyncedBg:       2,
+
+			GitIgnoredFg: 14,
+			GitIgnoredBg: 0,
+
+			AsdfFg: 15,
+			AsdfBg: 13,
+
+			GitSubmodulesFg: 14,
+			GitSubmodulesBg: 10,
+
+			GitPushAheadFg:  14,
+			GitPushAheadBg:  10,
+			GitPushBehindFg: 14,
+			GitPushBehindBg: 10,
+
+			SSHAgentFg:      8,
+			SSHAgentBg:      9,
+			SSHAgentEmptyFg: 15,
+			SSHAgentEmptyBg: 5,
+
+			GitRemoteFg: 12,
+			GitRemoteBg: 0,
+
+			VaultFg:        15,
+			VaultBg:        4,
+			VaultInvalidFg: 15,
+			VaultInvalidBg: 5,
+
+			CmdStickyFg: 5,
+			CmdStickyBg: 0,
+
+			CwdGradientStartFg: 14,
+			CwdGradientEndFg:   14,
+
+			TodoFg: 15,
+			TodoBg: 3,
+
+			GitBehindDefaultFg: 14,
+			GitBehindDefaultBg: 10,
+
+			EditorFg: 15,
+			EditorBg: 6,
+
+			GitPushTargetFg: 12,
+			GitPushTargetBg: 0,
+
+			GitAgeFg: 12,
+			GitAgeBg: 0,
+
+			GitUnpushedFg: 15,
+			GitUnpushedBg: 1,
+
+			GitConflictProminentFg: 3,
+			GitConflictProminentBg: 1,
+
+			PyenvFg: 3,
+			PyenvBg: 4,
+
+			GitGCHintFg: 15,
+			GitGCHintBg: 9,
+
+			AzureFg: 15,
+			AzureBg: 4,
+
+			GithubFg: 15,
+			GithubBg: 0,
+
+			GitMergeBaseFg: 14,
+			GitMergeBaseBg: 0,
+
+			GitNestedFg: 14,
+			GitNestedBg: 0,
+
+			FillFg: 10,
+			FillBg: 0,
+
+			LastCommandFg: 14,
+			LastCommandBg: 0,
+
+			MuxStateFg: 15,
+			MuxStateBg: 3,
+
+			ContainerFg: 15,
+			ContainerBg: 13,
+
+			GitCommitSubjectFg: 14,
+			GitCommitSubjectBg: 0,
+
+			GitOperationFg: 15,
+			GitOperationBg: 9,
+
+			GitRemoteCountFg: 12,
+			GitRemoteCountBg: 0,
 		},
 		"solarized-light16": {
 			Reset:              0,
@@ -1595,6 +2409,108 @@ var defaults = Config{
 			ViModeCommandBg: 250,
 			ViModeInsertFg:  22,
 			ViModeInsertBg:  70,
+
+			GitSyncAheadFg:    15,
+			GitSyncAheadBg:    4,
+			GitSyncBehindFg:   15,
+			GitSyncBehindBg:   9,
+			GitSyncDivergedFg: 15,
+			GitSyncDivergedBg: 1,
+			GitSyncedFg:       15,
+			GitSyncedBg:       2,
+
+			GitIgnoredFg: 14,
+			GitIgnoredBg: 0,
+
+			AsdfFg: 15,
+			AsdfBg: 13,
+
+			GitSubmodulesFg: 14,
+			GitSubmodulesBg: 10,
+
+			GitPushAheadFg:  14,
+			GitPushAheadBg:  10,
+			GitPushBehindFg: 14,
+			GitPushBehindBg: 10,
+
+			SSHAgentFg:      8,
+			SSHAgentBg:      9,
+			SSHAgentEmptyFg: 15,
+			SSHAgentEmptyBg: 5,
+
+			GitRemoteFg: 12,
+			GitRemoteBg: 7,
+
+			VaultFg:        15,
+			VaultBg:        4,
+			VaultInvalidFg: 15,
+			VaultInvalidBg: 5,
+
+			CmdStickyFg: 5,
+			CmdStickyBg: 7,
+
+			CwdGradientStartFg: 14,
+			CwdGradientEndFg:   14,
+
+			TodoFg: 15,
+			TodoBg: 3,
+
+			GitBehindDefaultFg: 14,
+			GitBehindDefaultBg: 10,
+
+			EditorFg: 15,
+			EditorBg: 6,
+
+			GitPushTargetFg: 12,
+			GitPushTargetBg: 7,
+
+			GitAgeFg: 12,
+			GitAgeBg: 7,
+
+			GitUnpushedFg: 15,
+			GitUnpushedBg: 1,
+
+			GitConflictProminentFg: 3,
+			GitConflictProminentBg: 1,
+
+			PyenvFg: 3,
+			PyenvBg: 4,
+
+			GitGCHintFg: 15,
+			GitGCHintBg: 9,
+
+			AzureFg: 15,
+			AzureBg: 4,
+
+			GithubFg: 15,
+			GithubBg: 0,
+
+			GitMergeBaseFg: 10,
+			GitMergeBaseBg: 7,
+
+			GitNestedFg: 10,
+			GitNestedBg: 7,
+
+			FillFg: 14,
+			FillBg: 7,
+
+			LastCommandFg: 10,
+			LastCommandBg: 7,
+
+			MuxStateFg: 15,
+			MuxStateBg: 3,
+
+			ContainerFg: 15,
+			ContainerBg: 13,
+
+			GitCommitSubjectFg: 10,
+			GitCommitSubjectBg: 7,
+
+			GitOperationFg: 15,
+			GitOperationBg: 9,
+
+			GitRemoteCountFg: 12,
+			GitRemoteCountBg: 7,
 		},
 		"gruvbox": {
 			/* based on https://github.com/b-ryan/powerline-shell/blob/master/powerline_shell/themes/gruvbox.py */
@@ -1677,10 +2593,203 @@ var defaults = Config{
 			ViModeCommandBg: 250,
 			ViModeInsertFg:  22,
 			ViModeInsertBg:  70,
+
+			GitSyncAheadFg:    gruvbox_light0,
+			GitSyncAheadBg:    gruvbox_faded_blue,
+			GitSyncBehindFg:   gruvbox_light0,
+			GitSyncBehindBg:   gruvbox_neutral_orange,
+			GitSyncDivergedFg: gruvbox_light0,
+			GitSyncDivergedBg: gruvbox_neutral_red,
+			GitSyncedFg:       gruvbox_light0,
+			GitSyncedBg:       gruvbox_faded_green,
+
+			GitIgnoredFg: gruvbox_dark_gray,
+			GitIgnoredBg: gruvbox_dark1,
+
+			AsdfFg: gruvbox_light0,
+			AsdfBg: gruvbox_faded_purple,
+
+			GitSubmodulesFg: gruvbox_light3,
+			GitSubmodulesBg: gruvbox_dark2,
+
+			GitPushAheadFg:  gruvbox_light3,
+			GitPushAheadBg:  gruvbox_dark2,
+			GitPushBehindFg: gruvbox_light3,
+			GitPushBehindBg: gruvbox_dark2,
+
+			SSHAgentFg:      gruvbox_light0,
+			SSHAgentBg:      gruvbox_faded_purple,
+			SSHAgentEmptyFg: gruvbox_light0,
+			SSHAgentEmptyBg: gruvbox_neutral_red,
+
+			GitRemoteFg: gruvbox_light4,
+			GitRemoteBg: gruvbox_dark1,
+
+			VaultFg:        gruvbox_light0,
+			VaultBg:        gruvbox_faded_blue,
+			VaultInvalidFg: gruvbox_light0,
+			VaultInvalidBg: gruvbox_neutral_red,
+
+			CmdStickyFg: gruvbox_light4,
+			CmdStickyBg: gruvbox_faded_red,
+
+			CwdGradientStartFg: gruvbox_dark4,
+			CwdGradientEndFg:   gruvbox_light3,
+
+			TodoFg: gruvbox_light1,
+			TodoBg: gruvbox_faded_yellow,
+
+			GitBehindDefaultFg: gruvbox_light3,
+			GitBehindDefaultBg: gruvbox_dark2,
+
+			EditorFg: gruvbox_light0,
+			EditorBg: gruvbox_faded_aqua,
+
+			GitPushTargetFg: gruvbox_light4,
+			GitPushTargetBg: gruvbox_dark1,
+
+			GitAgeFg: gruvbox_light4,
+			GitAgeBg: gruvbox_dark1,
+
+			GitUnpushedFg: gruvbox_light0,
+			GitUnpushedBg: gruvbox_bright_red,
+
+			GitConflictProminentFg: gruvbox_bright_yellow,
+			GitConflictProminentBg: gruvbox_bright_red,
+
+			PyenvFg: gruvbox_light1,
+			PyenvBg: gruvbox_faded_blue,
+
+			GitGCHintFg: gruvbox_light0,
+			GitGCHintBg: gruvbox_faded_orange,
+
+			AzureFg: gruvbox_light0,
+			AzureBg: gruvbox_faded_blue,
+
+			GithubFg: gruvbox_light0,
+			GithubBg: gruvbox_dark2,
+
+			GitMergeBaseFg: gruvbox_light4,
+			GitMergeBaseBg: gruvbox_dark1,
+
+			GitNestedFg: gruvbox_light4,
+			GitNestedBg: gruvbox_dark2,
+
+			FillFg: gruvbox_dark4,
+			FillBg: gruvbox_dark1,
+
+			LastCommandFg: gruvbox_light2,
+			LastCommandBg: gruvbox_dark2,
+
+			MuxStateFg: gruvbox_dark0,
+			MuxStateBg: gruvbox_bright_yellow,
+
+			ContainerFg: gruvbox_light0,
+			ContainerBg: gruvbox_faded_purple,
+
+			GitCommitSubjectFg: gruvbox_light4,
+			GitCommitSubjectBg: gruvbox_dark1,
+
+			GitOperationFg: gruvbox_light0,
+			GitOperationBg: gruvbox_neutral_orange,
+
+			GitRemoteCountFg: gruvbox_light4,
+			GitRemoteCountBg: gruvbox_dark1,
 		},
 	},
-	Time:   "15:04:05",
-	ViMode: "",
+	Time:                      "15:04:05",
+	ViMode:                    "",
+	GitSyncGlyphMode:          false,
+	GitUntrackedDirAsSingle:   false,
+	GitShowIgnoredCount:       false,
+	AsdfTools:                 []string{},
+	TemplateSegment:           "",
+	GitDetachedFormat:         "{short}",
+	GitHideInHome:             false,
+	GitDetachedAheadBehind:    false,
+	GitHideWhenClean:          false,
+	Output:                    "prompt",
+	GitShowSubmoduleCount:     false,
+	NerdFontVersion:           0,
+	GitShowPushAheadBehind:    false,
+	Debug:                     false,
+	TitleFormat:               "",
+	GitStatsThinSeparator:     false,
+	GitShowRepoType:           false,
+	GitStashFileCount:         false,
+	GitShowGerrit:             false,
+	EvalSafe:                  false,
+	GitBranchBasenameOnly:     false,
+	GitAheadBehindCap:         1000,
+	GitFSMonitor:              false,
+	GitShowRemoteName:         false,
+	KubeShowHelm:              false,
+	GitStatsSharedBg:          -1,
+	VaultCheckToken:           false,
+	ExitSticky:                false,
+	GitDivergedGlyph:          false,
+	CwdGradient:               false,
+	GitShowBehindDefault:      false,
+	Reverse:                   false,
+	GitPushTargetBadge:        false,
+	GitCLIFallbackOnError:     false,
+	GitStashClassify:          false,
+	BuildStatusFile:           ".build-status",
+	UserhostFormat:            "{user}@{host}",
+	DefaultUser:               "",
+	HideDefaultUser:           false,
+	GitShowCommitAge:          false,
+	GitAgeDate:                "commit",
+	GitShowUnpushedIndicator:  false,
+	GitConflictProminent:      false,
+	CwdIgnoreErrors:           false,
+	CwdNativeToken:            false,
+	PythonPrefer:              "venv",
+	GitRecurseSubmodules:      false,
+	GitBranchColorBySync:      false,
+	CwdHeadCount:              1,
+	CwdTailCount:              2,
+	GitUntrackedNotDirty:      false,
+	GitShowGCHint:             false,
+	GitGCHintThreshold:        6700,
+	GCPShowAccount:            false,
+	GCPConfigFilesOnly:        false,
+	GitShowNoUpstream:         false,
+	GitStatsBeforeBranch:      false,
+	GitSymbolBranch:           "",
+	GitSymbolDetached:         "",
+	GitSymbolAhead:            "",
+	GitSymbolBehind:           "",
+	GitSymbolStaged:           "",
+	GitSymbolNotStaged:        "",
+	GitSymbolUntracked:        "",
+	GitSymbolConflicted:       "",
+	GitSymbolStashed:          "",
+	Side:                      "left",
+	GithubCacheFile:           "",
+	GithubCacheMaxAge:         900,
+	ColorMode:                 "256",
+	DirEnteredAt:              "",
+	GitShowMergeBase:          false,
+	GitMergeBaseRef:           "",
+	GitShowNested:             false,
+	FillToWidth:               false,
+	FillChar:                  " ",
+	MultilineContinuation:     "",
+	LastCommand:               "",
+	LastCommandMaxLength:      20,
+	GitSkipOnFS:               []string{},
+	GitDetectLock:             false,
+	MuxStates:                 []string{"zoomed", "copy-mode"},
+	Group:                     []string{},
+	GitSubmodulePointers:      false,
+	CwdMaxLength:              0,
+	GitShowCommitSubject:      false,
+	GitCommitSubjectMaxLength: 30,
+	PostHook:                  "",
+	GitUpstreamFallback:       false,
+	GitShowRemoteCount:        false,
+	GitLiteStatus:             false,
 }
 
 const (
@@ -0,0 +1,45 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// filesystemMagics names the filesystems statfs reports by magic number, as
+// stat -f does
+var filesystemMagics = map[uint32]string{
+	0x00011954: "ufs",
+	0x00C36400: "ceph",
+	0x01021994: "tmpfs",
+	0x01021997: "v9fs",
+	0x0BD00BD0: "lustre",
+	0x2FC12FC1: "zfs",
+	0x5346414F: "afs",
+	0x517B:     "smb",
+	0x58465342: "xfs",
+	0x6969:     "nfs",
+	0x65735546: "fuse",
+	0x794C7630: "overlayfs",
+	0x9123683E: "btrfs",
+	0xEF53:     "ext4",
+	0xF2F52010: "f2fs",
+	0xFE534D42: "smb2",
+	0xFF534D42: "cifs",
+}
+
+// filesystemType returns the type of the filesystem path is on
+func filesystemType(path string) (string, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return "", err
+	}
+	magic := uint32(st.Type)
+	if name, ok := filesystemMagics[magic]; ok {
+		return name, nil
+	}
+	return fmt.Sprintf("0x%x", magic), nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package main
+
+import "errors"
+
+// filesystemType isn't supported on this platform
+func filesystemType(path string) (string, error) {
+	return "", errors.New("filesystem type detection not supported")
+}
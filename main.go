@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 
 	pwl "github.com/justjanne/powerline-go/powerline"
@@ -45,11 +46,16 @@ func pathExists(path string) bool {
 	return true
 }
 
-func getValidCwd() string {
+// getValidCwd returns the current directory, warning if it no longer exists
+// unless ignoreErrors is set
+func getValidCwd(ignoreErrors bool) string {
 	cwd, err := os.Getwd()
 	if err != nil {
 		var exists bool
 		cwd, exists = os.LookupEnv("PWD")
+		if !exists && ignoreErrors {
+			return ""
+		}
 		if !exists {
 			warn("Your current directory is invalid.")
 			print("> ")
@@ -64,7 +70,7 @@ func getValidCwd() string {
 		parts = parts[:len(parts)-1]
 		up = strings.Join(parts, string(os.PathSeparator))
 	}
-	if cwd != up {
+	if cwd != up && !ignoreErrors {
 		warn("Your current directory is invalid. Lowest valid directory: " + up)
 	}
 	return cwd
@@ -111,6 +117,22 @@ var modules = map[string]func(*powerline) []pwl.Segment{
 	"vi-mode":             segmentViMode,
 	"wsl":                 segmentWSL,
 	"nix-shell":           segmentNixShell,
+	"asdf":                segmentAsdf,
+	"template":            segmentTemplate,
+	"sshagent":            segmentSSHAgent,
+	"vault":               segmentVault,
+	"todo":                segmentTodo,
+	"editor":              segmentEditor,
+	"chezmoi":             segmentChezmoi,
+	"build-status":        segmentBuildStatus,
+	"userhost":            segmentUserhost,
+	"pyenv":               segmentPyenv,
+	"azure":               segmentAzure,
+	"github":              segmentGithub,
+	"dirtime":             segmentDirTime,
+	"lastcmd":             segmentLastCommand,
+	"mux-state":           segmentMuxState,
+	"container":           segmentContainer,
 }
 
 func comments(lines ...string) string {
@@ -125,12 +147,13 @@ func main() {
 	flag.Parse()
 
 	cfg := defaults
-	err := cfg.Load()
+	err := cfg.Load(*args.ConfigFiles...)
 	if err != nil {
 		println("Error loading config")
 		println(err.Error())
 	}
 
+	errorSet := false
 	flag.Visit(func(f *flag.Flag) {
 		switch f.Name {
 		case "cwd-mode":
@@ -163,6 +186,7 @@ func main() {
 			cfg.GitMode = *args.GitMode
 		case "mode":
 			cfg.Mode = *args.Mode
+			cfg.ModeSet = true
 		case "theme":
 			cfg.Theme = *args.Theme
 		case "shell":
@@ -173,12 +197,47 @@ func main() {
 			cfg.ModulesRight = strings.Split(*args.ModulesRight, ",")
 		case "priority":
 			cfg.Priority = strings.Split(*args.Priority, ",")
+		case "segment-priorities":
+			// the map of the defaults is shared, so fill a copy
+			priorities := PriorityMap{}
+			for name, priority := range cfg.SegmentPriorities {
+				priorities[name] = priority
+			}
+			cfg.SegmentPriorities = priorities
+			for _, pair := range strings.Split(*args.SegmentPriorities, ",") {
+				kv := strings.SplitN(pair, "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				priority, err := strconv.Atoi(kv[1])
+				if err != nil {
+					warn("Ignoring invalid segment priority " + pair)
+					continue
+				}
+				cfg.SegmentPriorities[kv[0]] = priority
+			}
+		case "segment-min-width":
+			for _, pair := range strings.Split(*args.SegmentMinWidths, ",") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				width, err := strconv.Atoi(kv[1])
+				if err != nil {
+					warn("Ignoring invalid segment minimum width " + pair)
+					continue
+				}
+				cfg.SegmentMinWidths[kv[0]] = width
+			}
 		case "max-width":
 			cfg.MaxWidthPercentage = *args.MaxWidthPercentage
 		case "truncate-segment-width":
 			cfg.TruncateSegmentWidth = *args.TruncateSegmentWidth
 		case "error":
 			cfg.PrevError = *args.PrevError
+			errorSet = true
+		case "command-number":
+			cfg.CommandNumber = *args.CommandNumber
 		case "numeric-exit-codes":
 			cfg.NumericExitCodes = *args.NumericExitCodes
 		case "ignore-repos":
@@ -216,9 +275,195 @@ func main() {
 			cfg.Time = *args.Time
 		case "vi-mode":
 			cfg.ViMode = *args.ViMode
+		case "git-sync-glyph-mode":
+			cfg.GitSyncGlyphMode = *args.GitSyncGlyphMode
+		case "git-untracked-dir-as-single":
+			cfg.GitUntrackedDirAsSingle = *args.GitUntrackedDirAsSingle
+		case "git-show-ignored-count":
+			cfg.GitShowIgnoredCount = *args.GitShowIgnoredCount
+		case "asdf-tools":
+			cfg.AsdfTools = strings.Split(*args.AsdfTools, ",")
+		case "template-segment":
+			cfg.TemplateSegment = *args.TemplateSegment
+		case "git-detached-format":
+			cfg.GitDetachedFormat = *args.GitDetachedFormat
+		case "git-hide-in-home":
+			cfg.GitHideInHome = *args.GitHideInHome
+		case "git-detached-ahead-behind":
+			cfg.GitDetachedAheadBehind = *args.GitDetachedAheadBehind
+		case "git-hide-when-clean":
+			cfg.GitHideWhenClean = *args.GitHideWhenClean
+		case "output":
+			cfg.Output = *args.Output
+		case "git-show-submodule-count":
+			cfg.GitShowSubmoduleCount = *args.GitShowSubmoduleCount
+		case "nerd-font-version":
+			cfg.NerdFontVersion = *args.NerdFontVersion
+		case "git-show-push-ahead-behind":
+			cfg.GitShowPushAheadBehind = *args.GitShowPushAheadBehind
+		case "debug":
+			cfg.Debug = *args.Debug
+		case "title-format":
+			cfg.TitleFormat = *args.TitleFormat
+		case "git-stats-thin-separator":
+			cfg.GitStatsThinSeparator = *args.GitStatsThinSeparator
+		case "git-show-repo-type":
+			cfg.GitShowRepoType = *args.GitShowRepoType
+		case "git-stash-file-count":
+			cfg.GitStashFileCount = *args.GitStashFileCount
+		case "git-show-gerrit":
+			cfg.GitShowGerrit = *args.GitShowGerrit
+		case "eval-safe":
+			cfg.EvalSafe = *args.EvalSafe
+		case "git-branch-basename-only":
+			cfg.GitBranchBasenameOnly = *args.GitBranchBasenameOnly
+		case "git-ahead-behind-cap":
+			cfg.GitAheadBehindCap = *args.GitAheadBehindCap
+		case "git-fsmonitor":
+			cfg.GitFSMonitor = *args.GitFSMonitor
+		case "git-show-remote-name":
+			cfg.GitShowRemoteName = *args.GitShowRemoteName
+		case "kube-show-helm":
+			cfg.KubeShowHelm = *args.KubeShowHelm
+		case "git-stats-shared-bg":
+			cfg.GitStatsSharedBg = *args.GitStatsSharedBg
+		case "vault-check-token":
+			cfg.VaultCheckToken = *args.VaultCheckToken
+		case "exit-sticky":
+			cfg.ExitSticky = *args.ExitSticky
+		case "git-diverged-glyph":
+			cfg.GitDivergedGlyph = *args.GitDivergedGlyph
+		case "cwd-gradient":
+			cfg.CwdGradient = *args.CwdGradient
+		case "git-show-behind-default":
+			cfg.GitShowBehindDefault = *args.GitShowBehindDefault
+		case "reverse":
+			cfg.Reverse = *args.Reverse
+		case "git-push-target-badge":
+			cfg.GitPushTargetBadge = *args.GitPushTargetBadge
+		case "git-cli-fallback-on-error":
+			cfg.GitCLIFallbackOnError = *args.GitCLIFallbackOnError
+		case "git-stash-classify":
+			cfg.GitStashClassify = *args.GitStashClassify
+		case "build-status-file":
+			cfg.BuildStatusFile = *args.BuildStatusFile
+		case "userhost-format":
+			cfg.UserhostFormat = *args.UserhostFormat
+		case "default-user":
+			cfg.DefaultUser = *args.DefaultUser
+		case "hide-default-user":
+			cfg.HideDefaultUser = *args.HideDefaultUser
+		case "git-show-commit-age":
+			cfg.GitShowCommitAge = *args.GitShowCommitAge
+		case "git-age-date":
+			cfg.GitAgeDate = *args.GitAgeDate
+		case "git-show-unpushed-indicator":
+			cfg.GitShowUnpushedIndicator = *args.GitShowUnpushedIndicator
+		case "git-conflict-prominent":
+			cfg.GitConflictProminent = *args.GitConflictProminent
+		case "cwd-ignore-errors":
+			cfg.CwdIgnoreErrors = *args.CwdIgnoreErrors
+		case "cwd-native-token":
+			cfg.CwdNativeToken = *args.CwdNativeToken
+		case "python-prefer":
+			cfg.PythonPrefer = *args.PythonPrefer
+		case "git-recurse-submodules":
+			cfg.GitRecurseSubmodules = *args.GitRecurseSubmodules
+		case "git-branch-color-by-sync":
+			cfg.GitBranchColorBySync = *args.GitBranchColorBySync
+		case "cwd-head-count":
+			cfg.CwdHeadCount = *args.CwdHeadCount
+		case "cwd-tail-count":
+			cfg.CwdTailCount = *args.CwdTailCount
+		case "git-untracked-not-dirty":
+			cfg.GitUntrackedNotDirty = *args.GitUntrackedNotDirty
+		case "git-show-gc-hint":
+			cfg.GitShowGCHint = *args.GitShowGCHint
+		case "git-gc-hint-threshold":
+			cfg.GitGCHintThreshold = *args.GitGCHintThreshold
+		case "gcp-show-account":
+			cfg.GCPShowAccount = *args.GCPShowAccount
+		case "gcp-config-files-only":
+			cfg.GCPConfigFilesOnly = *args.GCPConfigFilesOnly
+		case "git-show-no-upstream":
+			cfg.GitShowNoUpstream = *args.GitShowNoUpstream
+		case "git-stats-before-branch":
+			cfg.GitStatsBeforeBranch = *args.GitStatsBeforeBranch
+		case "git-symbol-branch":
+			cfg.GitSymbolBranch = *args.GitSymbolBranch
+		case "git-symbol-detached":
+			cfg.GitSymbolDetached = *args.GitSymbolDetached
+		case "git-symbol-ahead":
+			cfg.GitSymbolAhead = *args.GitSymbolAhead
+		case "git-symbol-behind":
+			cfg.GitSymbolBehind = *args.GitSymbolBehind
+		case "git-symbol-staged":
+			cfg.GitSymbolStaged = *args.GitSymbolStaged
+		case "git-symbol-not-staged":
+			cfg.GitSymbolNotStaged = *args.GitSymbolNotStaged
+		case "git-symbol-untracked":
+			cfg.GitSymbolUntracked = *args.GitSymbolUntracked
+		case "git-symbol-conflicted":
+			cfg.GitSymbolConflicted = *args.GitSymbolConflicted
+		case "git-symbol-stashed":
+			cfg.GitSymbolStashed = *args.GitSymbolStashed
+		case "side":
+			cfg.Side = *args.Side
+		case "github-cache-file":
+			cfg.GithubCacheFile = *args.GithubCacheFile
+		case "github-cache-max-age":
+			cfg.GithubCacheMaxAge = *args.GithubCacheMaxAge
+		case "color-mode":
+			cfg.ColorMode = *args.ColorMode
+		case "dir-entered-at":
+			cfg.DirEnteredAt = *args.DirEnteredAt
+		case "git-show-merge-base":
+			cfg.GitShowMergeBase = *args.GitShowMergeBase
+		case "git-merge-base-ref":
+			cfg.GitMergeBaseRef = *args.GitMergeBaseRef
+		case "git-show-nested":
+			cfg.GitShowNested = *args.GitShowNested
+		case "fill-to-width":
+			cfg.FillToWidth = *args.FillToWidth
+		case "fill-char":
+			cfg.FillChar = *args.FillChar
+		case "multiline-continuation":
+			cfg.MultilineContinuation = *args.MultilineContinuation
+		case "last-command":
+			cfg.LastCommand = *args.LastCommand
+		case "last-command-max-length":
+			cfg.LastCommandMaxLength = *args.LastCommandMaxLength
+		case "git-skip-on-fs":
+			cfg.GitSkipOnFS = strings.Split(*args.GitSkipOnFS, ",")
+		case "git-detect-lock":
+			cfg.GitDetectLock = *args.GitDetectLock
+		case "mux-states":
+			cfg.MuxStates = strings.Split(*args.MuxStates, ",")
+		case "group":
+			cfg.Group = strings.Split(*args.Group, ",")
+		case "git-submodule-pointers":
+			cfg.GitSubmodulePointers = *args.GitSubmodulePointers
+		case "cwd-max-length":
+			cfg.CwdMaxLength = *args.CwdMaxLength
+		case "git-show-commit-subject":
+			cfg.GitShowCommitSubject = *args.GitShowCommitSubject
+		case "git-commit-subject-max-length":
+			cfg.GitCommitSubjectMaxLength = *args.GitCommitSubjectMaxLength
+		case "post-hook":
+			cfg.PostHook = *args.PostHook
+		case "git-upstream-fallback":
+			cfg.GitUpstreamFallback = *args.GitUpstreamFallback
+		case "git-show-remote-count":
+			cfg.GitShowRemoteCount = *args.GitShowRemoteCount
+		case "git-lite-status":
+			cfg.GitLiteStatus = *args.GitLiteStatus
 		}
 	})
 
+	if cfg.ExitSticky {
+		cfg.StickyError = updateStickyExit(exitStatePath(), cfg.CommandNumber, errorSet, cfg.PrevError)
+	}
+
 	if strings.HasSuffix(cfg.Theme, ".json") {
 		file, err := ioutil.ReadFile(cfg.Theme)
 		if err == nil {
@@ -247,10 +492,31 @@ func main() {
 		}
 	}
 
-	p := newPowerline(cfg, getValidCwd(), alignLeft)
+	align := alignLeft
+	if cfg.Side == "right" {
+		align = alignRight
+	}
+	p := newPowerline(cfg, getValidCwd(cfg.CwdIgnoreErrors), align)
+	if cfg.Output == "json" {
+		out, err := p.drawJSON()
+		if err != nil {
+			println("Error encoding segments")
+			println(err.Error())
+			os.Exit(1)
+		}
+		if cfg.PostHook != "" {
+			out = runPostHook(cfg.PostHook, out)
+		}
+		fmt.Print(out)
+		return
+	}
 	if p.supportsRightModules() && p.hasRightModules() && !cfg.Eval {
 		panic("Flag '-modules-right' requires '-eval' mode.")
 	}
 
-	fmt.Print(p.draw())
+	prompt := p.draw()
+	if cfg.PostHook != "" {
+		prompt = runPostHook(cfg.PostHook, prompt)
+	}
+	fmt.Print(prompt)
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const postHookTimeout = 500 * time.Millisecond
+
+// runPostHook passes the rendered prompt to the hook script on stdin and
+// returns what it prints, or the prompt unchanged if the hook fails or takes
+// too long
+func runPostHook(hook string, prompt string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), postHookTimeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, hook)
+	command.Stdin = strings.NewReader(prompt)
+	var stdout bytes.Buffer
+	command.Stdout = &stdout
+	// children of the hook may keep its output open after it is killed, so
+	// don't wait for them
+	done := make(chan error, 1)
+	go func() { done <- command.Run() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			warn("Ignoring -post-hook " + hook + ": " + err.Error())
+			return prompt
+		}
+		return strings.TrimSuffix(stdout.String(), "\n")
+	case <-ctx.Done():
+		warn("Ignoring -post-hook " + hook + ": " + ctx.Err().Error())
+		return prompt
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func Test_runPostHook(t *testing.T) {
+	dir := t.TempDir()
+	script := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755)
+		return path
+	}
+
+	tests := []struct {
+		hook string
+		want string
+	}{
+		{script("cat", "cat"), "~ > "},
+		{script("replace", "tr '~' '@'; echo"), "@ > "},
+		// failures fall back to the unmodified prompt
+		{script("fail", "echo broken; exit 1"), "~ > "},
+		{script("slow", "sleep 5"), "~ > "},
+		{filepath.Join(dir, "missing"), "~ > "},
+	}
+	for _, tt := range tests {
+		if got := runPostHook(tt.hook, "~ > "); got != tt.want {
+			t.Errorf("runPostHook(%s) = %q, want %q", filepath.Base(tt.hook), got, tt.want)
+		}
+	}
+}
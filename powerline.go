@@ -2,13 +2,17 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/user"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	pwl "github.com/justjanne/powerline-go/powerline"
 	"github.com/mattn/go-runewidth"
@@ -24,15 +28,20 @@ type ShellInfo struct {
 	EscapedDollar         string
 	EscapedBacktick       string
 	EscapedBackslash      string
+	EscapedPercent        string
 	EvalPromptPrefix      string
 	EvalPromptSuffix      string
 	EvalPromptRightPrefix string
 	EvalPromptRightSuffix string
+	TitleTemplate         string
+	// CwdToken is the prompt escape the shell expands to the current directory
+	CwdToken string
 }
 
 type powerline struct {
 	cfg            Config
 	cwd            string
+	cwdDeleted     bool
 	userInfo       user.User
 	userIsAdmin    bool
 	hostname       string
@@ -40,6 +49,7 @@ type powerline struct {
 	theme          Theme
 	shell          ShellInfo
 	reset          string
+	colors         int
 	symbols        SymbolTemplate
 	priorities     map[string]int
 	ignoreRepos    map[string]bool
@@ -47,17 +57,40 @@ type powerline struct {
 	curSegment     int
 	align          alignment
 	rightPowerline *powerline
+	branchOnce     sync.Once
+	branch         string
+}
+
+// directoryModules are the modules that look at the contents of the current
+// directory, and are skipped if it has been deleted
+var directoryModules = map[string]bool{
+	"asdf":         true,
+	"build-status": true,
+	"bzr":          true,
+	"chezmoi":      true,
+	"dotenv":       true,
+	"fossil":       true,
+	"git":          true,
+	"gitlite":      true,
+	"hg":           true,
+	"perms":        true,
+	"pyenv":        true,
+	"svn":          true,
+	"todo":         true,
+	"venv":         true,
 }
 
 type prioritizedSegments struct {
-	i    int
-	segs []pwl.Segment
+	i       int
+	segs    []pwl.Segment
+	elapsed time.Duration
 }
 
 func newPowerline(cfg Config, cwd string, align alignment) *powerline {
 	p := new(powerline)
 	p.cfg = cfg
 	p.cwd = cwd
+	p.cwdDeleted = cfg.CwdIgnoreErrors && !pathExists(cwd)
 	userInfo, err := user.Current()
 	if userInfo != nil && err == nil {
 		p.userInfo = *userInfo
@@ -80,6 +113,18 @@ func newPowerline(cfg Config, cwd string, align alignment) *powerline {
 	p.userIsAdmin = userIsAdmin()
 
 	p.theme = cfg.Themes[cfg.Theme]
+	p.colors = terminalColors(cfg.ColorMode)
+	if cfg.GitStatsSharedBg >= 0 && cfg.GitStatsSharedBg <= 255 {
+		p.theme = p.theme.withGitStatsBg(uint8(cfg.GitStatsSharedBg))
+	}
+	if cfg.Shell == "autodetect" && isDumbTerminal() {
+		// dumb terminals don't understand the shells' non-printing wrappers,
+		// and usually don't have powerline fonts either
+		cfg.Shell = "bare"
+		if !cfg.ModeSet && cfg.Mode == defaults.Mode {
+			cfg.Mode = "compatible"
+		}
+	}
 	if cfg.Shell == "autodetect" {
 		var shellExe string
 		proc, err := process.NewProcess(int32(os.Getppid()))
@@ -94,10 +139,22 @@ func newPowerline(cfg Config, cwd string, align alignment) *powerline {
 	p.shell = cfg.Shells[cfg.Shell]
 	p.reset = fmt.Sprintf(p.shell.ColorTemplate, "[0m")
 	p.symbols = cfg.Modes[cfg.Mode]
+	if cfg.NerdFontVersion > 0 {
+		symbols, ok := cfg.Modes[fmt.Sprintf("nerd-font-v%d", cfg.NerdFontVersion)]
+		if ok {
+			p.symbols = symbols
+		} else {
+			warn(fmt.Sprintf("Unsupported Nerd Font version %d", cfg.NerdFontVersion))
+		}
+	}
+	p.symbols = p.symbols.withGitOverrides(cfg)
 	p.priorities = make(map[string]int)
 	for idx, priority := range cfg.Priority {
 		p.priorities[priority] = len(cfg.Priority) - idx
 	}
+	for name, priority := range cfg.SegmentPriorities {
+		p.priorities[name] = priority
+	}
 	p.align = align
 	p.ignoreRepos = make(map[string]bool)
 	for _, r := range cfg.IgnoreRepos {
@@ -120,11 +177,28 @@ func newPowerline(cfg Config, cwd string, align alignment) *powerline {
 	} else {
 		mods = cfg.ModulesRight
 	}
+	for _, module := range mods {
+		if module == "git" || module == "gitlite" {
+			if gitDir, found := findGitDir(p.cwd); found && !p.ignoreRepos[filepath.Dir(gitDir)] {
+				if values, err := gitRepoConfig(gitDir); err == nil {
+					p.cfg = applyGitRepoConfig(p.cfg, values)
+				}
+			}
+			break
+		}
+	}
 	initSegments(p, mods)
 
 	return p
 }
 
+// isDumbTerminal reports whether the prompt is drawn by a dumb terminal, like
+// the shell mode of emacs
+func isDumbTerminal() bool {
+	_, insideEmacs := os.LookupEnv("INSIDE_EMACS")
+	return insideEmacs || os.Getenv("TERM") == "dumb"
+}
+
 func detectShell(shellExe string) string {
 	var shell string
 	shellExe = path.Base(shellExe)
@@ -140,23 +214,40 @@ func detectShell(shellExe string) string {
 
 func initSegments(p *powerline, mods []string) {
 	orderedSegments := map[int][]pwl.Segment{}
+	elapsed := map[int]time.Duration{}
+	start := time.Now()
 	c := make(chan prioritizedSegments, len(mods))
 	wg := sync.WaitGroup{}
+	width := 0
+	if len(p.cfg.SegmentMinWidths) > 0 {
+		width = termWidth()
+	}
 	for i, module := range mods {
+		if minWidth, ok := p.cfg.SegmentMinWidths[module]; ok && width > 0 && width < minWidth {
+			p.debug(fmt.Sprintf("segment %s omitted, terminal is narrower than %d columns", module, minWidth))
+			continue
+		}
+		if p.cwdDeleted && directoryModules[module] {
+			p.debug(fmt.Sprintf("segment %s omitted, the current directory was deleted", module))
+			continue
+		}
 		wg.Add(1)
 		go func(w *sync.WaitGroup, i int, module string, c chan prioritizedSegments) {
+			start := time.Now()
 			elem, ok := modules[module]
 			if ok {
 				c <- prioritizedSegments{
-					i:    i,
-					segs: elem(p),
+					i:       i,
+					segs:    elem(p),
+					elapsed: time.Since(start),
 				}
 			} else {
 				s, ok := segmentPlugin(p, module)
 				if ok {
 					c <- prioritizedSegments{
-						i:    i,
-						segs: s,
+						i:       i,
+						segs:    s,
+						elapsed: time.Since(start),
 					}
 				} else {
 					println("Module not found: " + module)
@@ -169,18 +260,80 @@ func initSegments(p *powerline, mods []string) {
 	close(c)
 	for s := range c {
 		orderedSegments[s.i] = s.segs
+		elapsed[s.i] = s.elapsed
+	}
+	grouped := map[string]bool{}
+	for _, module := range p.cfg.Group {
+		grouped[module] = true
 	}
 	for i := 0; i < len(mods); i++ {
-		for _, seg := range orderedSegments[i] {
+		segs := orderedSegments[i]
+		if grouped[mods[i]] {
+			// adjacent modules of the group share one block
+			segs = append([]pwl.Segment{}, segs...)
+			for i+1 < len(mods) && grouped[mods[i+1]] {
+				i++
+				segs = append(segs, orderedSegments[i]...)
+			}
+			segs = p.groupSegments(segs)
+		}
+		for _, seg := range segs {
 			p.appendSegment(seg.Name, seg)
 		}
 	}
+	if p.cfg.Debug {
+		for i, module := range mods {
+			p.debug(fmt.Sprintf("segment %s took %s", module, elapsed[i]))
+		}
+		p.debugTiming("all segments", start)
+	}
+}
+
+var placeholderRegex = regexp.MustCompile(`\{[a-z]+\}`)
+
+// expandPlaceholders replaces {name} placeholders in format. Values are only
+// resolved when the placeholder is used, unknown placeholders are kept as is.
+func expandPlaceholders(format string, values map[string]func() string) string {
+	return placeholderRegex.ReplaceAllStringFunc(format, func(placeholder string) string {
+		if value, ok := values[placeholder[1:len(placeholder)-1]]; ok {
+			return value()
+		}
+		return placeholder
+	})
+}
+
+// debug prints msg to stderr if debugging output is enabled
+func (p *powerline) debug(msg string) {
+	if p.cfg.Debug {
+		fmt.Fprintln(os.Stderr, "[powerline-go]", msg)
+	}
+}
+
+// debugTiming prints how long step took since start if debugging output is
+// enabled
+func (p *powerline) debugTiming(step string, start time.Time) {
+	p.debug(fmt.Sprintf("%s took %s", step, time.Since(start)))
+}
+
+// gitBranch returns the git branch of the current directory, computing it at
+// most once per prompt
+func (p *powerline) gitBranch() string {
+	p.branchOnce.Do(func() {
+		out, err := runGitCommand("git", "rev-parse", "--abbrev-ref", "HEAD")
+		if err == nil {
+			p.branch = strings.TrimSpace(out)
+		}
+	})
+	return p.branch
 }
 
 func (p *powerline) color(prefix string, code uint8) string {
 	if code == p.theme.Reset {
 		return p.reset
 	}
+	if p.colors == 8 || p.colors == 16 {
+		return fmt.Sprintf(p.shell.ColorTemplate, fmt.Sprintf("[%sm", basicColorSGR(prefix, quantizeColor(code, p.colors))))
+	}
 	return fmt.Sprintf(p.shell.ColorTemplate, fmt.Sprintf("[%s;5;%dm", prefix, code))
 }
 
@@ -196,7 +349,49 @@ func (p *powerline) bgColor(code uint8) string {
 	return p.color("48", code)
 }
 
+// attributes returns the escape sequences turning the text attributes of
+// segment on and back off, or nothing if it has none
+func (p *powerline) attributes(segment pwl.Segment) (string, string) {
+	attributes := segment.Attributes()
+	if attributes == "" {
+		return "", ""
+	}
+	// 22 turns off both bold and faint
+	return fmt.Sprintf(p.shell.ColorTemplate, "["+attributes+"m"),
+		fmt.Sprintf(p.shell.ColorTemplate, "[22;23;24m")
+}
+
+// groupSegments draws segments as one block on the background of the first,
+// with thin separators between them
+func (p *powerline) groupSegments(segments []pwl.Segment) []pwl.Segment {
+	grouped := make([]pwl.Segment, 0, len(segments))
+	for _, segment := range segments {
+		if !segment.NewLine && strings.TrimSpace(segment.Content) != "" {
+			grouped = append(grouped, segment)
+		}
+	}
+	if len(grouped) < 2 {
+		return grouped
+	}
+	background := grouped[0].Background
+	for idx := range grouped {
+		grouped[idx].Background = background
+		if p.isRightPrompt() && idx != 0 {
+			grouped[idx].Separator = p.symbols.SeparatorReverseThin
+			grouped[idx].SeparatorForeground = p.theme.SeparatorFg
+		} else if !p.isRightPrompt() && idx != len(grouped)-1 {
+			grouped[idx].Separator = p.symbols.SeparatorThin
+			grouped[idx].SeparatorForeground = p.theme.SeparatorFg
+		}
+	}
+	return grouped
+}
+
 func (p *powerline) appendSegment(origin string, segment pwl.Segment) {
+	// segments without content would still be drawn as an empty block
+	if !segment.NewLine && strings.TrimSpace(segment.Content) == "" {
+		return
+	}
 	if segment.Foreground == segment.Background && segment.Background == 0 {
 		segment.Background = p.theme.DefaultBg
 		segment.Foreground = p.theme.DefaultFg
@@ -209,10 +404,20 @@ func (p *powerline) appendSegment(origin string, segment pwl.Segment) {
 		}
 	}
 	if segment.SeparatorForeground == 0 {
-		segment.SeparatorForeground = segment.Background
+		if p.theme.SeparatorOverride {
+			segment.SeparatorForeground = p.theme.SeparatorOverrideFg
+		} else {
+			segment.SeparatorForeground = segment.Background
+		}
 	}
 	segment.Priority += p.priorities[origin]
-	segment.Width = segment.ComputeWidth(p.cfg.Condensed)
+	contentWidth := pwl.StringWidth(segment.Content)
+	if segment.Width > 0 {
+		// segments drawing a shell escape set Width to the width of what the
+		// shell expands it to
+		contentWidth = segment.Width
+	}
+	segment.Width = segment.ComputeWidth(p.cfg.Condensed) - pwl.StringWidth(segment.Content) + contentWidth
 	if segment.NewLine {
 		p.newRow()
 	} else {
@@ -271,7 +476,7 @@ func (p *powerline) truncateRow(rowNum int) {
 
 				rowLength -= segment.Width
 
-				segment.Content = runewidth.Truncate(segment.Content, p.cfg.TruncateSegmentWidth-runewidth.StringWidth(segment.Separator)-3, "…")
+				segment.Content = runewidth.Truncate(segment.Content, p.cfg.TruncateSegmentWidth-pwl.StringWidth(segment.Separator)-3, "…")
 				segment.Width = segment.ComputeWidth(p.cfg.Condensed)
 
 				row = append(append(row[:minPriorityNotTruncatedSegmentID], segment), row[minPriorityNotTruncatedSegmentID+1:]...)
@@ -326,21 +531,93 @@ func (p *powerline) numEastAsianRunes(segmentContent *string) int {
 	return numEastAsianRunes
 }
 
+// reverseRow reverses the order of the segments in a row and flips their
+// separators to point the other way
+func (p *powerline) reverseRow(rowNum int) {
+	row := p.Segments[rowNum]
+	for i, j := 0, len(row)-1; i < j; i, j = i+1, j-1 {
+		row[i], row[j] = row[j], row[i]
+	}
+	flipped := map[string]string{
+		p.symbols.Separator:            p.symbols.SeparatorReverse,
+		p.symbols.SeparatorThin:        p.symbols.SeparatorReverseThin,
+		p.symbols.SeparatorReverse:     p.symbols.Separator,
+		p.symbols.SeparatorReverseThin: p.symbols.SeparatorThin,
+	}
+	for idx := range row {
+		if separator, ok := flipped[row[idx].Separator]; ok {
+			row[idx].Separator = separator
+		}
+	}
+}
+
+// fillsRow reports whether row rowNum is filled to the terminal width. Only
+// rows of the left prompt followed by a line break are, so the cursor keeps
+// room for the command, or the last one up to the right prompt next to it.
+func (p *powerline) fillsRow(rowNum int) bool {
+	return p.cfg.FillToWidth && p.align == alignLeft &&
+		(rowNum < len(p.Segments)-1 || p.cfg.PromptOnNewLine || p.hasRightModules())
+}
+
+// rightPromptWidth returns the printable width of the right prompt, which is
+// drawn with a leading space
+func (p *powerline) rightPromptWidth() int {
+	p.rightPowerline.truncateRow(0)
+	width := 1
+	for _, segment := range p.rightPowerline.Segments[0] {
+		if segment.HideSeparators {
+			width += pwl.StringWidth(segment.Content)
+		} else {
+			width += segment.Width
+		}
+	}
+	return width
+}
+
+// drawFill fills the rest of a row of the given printable width with the fill
+// character
+func (p *powerline) drawFill(buffer *bytes.Buffer, rowWidth int) {
+	fillChar := p.cfg.FillChar
+	if pwl.StringWidth(fillChar) < 1 {
+		fillChar = " "
+	}
+	n := (termWidth() - rowWidth) / pwl.StringWidth(fillChar)
+	if n <= 0 {
+		return
+	}
+	buffer.WriteString(p.fgColor(p.theme.FillFg))
+	buffer.WriteString(p.bgColor(p.theme.FillBg))
+	buffer.WriteString(strings.Repeat(fillChar, n))
+	buffer.WriteString(p.reset)
+}
+
 func (p *powerline) drawRow(rowNum int, buffer *bytes.Buffer) {
 	row := p.Segments[rowNum]
 	numEastAsianRunes := 0
+	rowWidth := 0
+	fill := p.fillsRow(rowNum)
+
+	// Lines after a line break start with the continuation glyph
+	if rowNum > 0 && p.align == alignLeft && p.cfg.MultilineContinuation != "" {
+		buffer.WriteString(escapeVariables(p, p.cfg.MultilineContinuation))
+		rowWidth += pwl.StringWidth(p.cfg.MultilineContinuation)
+	}
 
 	// Prepend padding
 	if p.isRightPrompt() {
 		buffer.WriteRune(' ')
 	}
+	// separators point left on right prompts, unless they are reversed
+	leftPointing := p.isRightPrompt() != p.cfg.Reverse
 	for idx, segment := range row {
 		if segment.HideSeparators {
 			buffer.WriteString(segment.Content)
+			rowWidth += pwl.StringWidth(segment.Content)
 			continue
 		}
+		rowWidth += segment.Width
 		var separatorBackground string
-		if p.isRightPrompt() {
+		if leftPointing {
 			if idx == 0 {
 				separatorBackground = p.reset
 			} else {
@@ -352,7 +629,9 @@ func (p *powerline) drawRow(rowNum int, buffer *bytes.Buffer) {
 			buffer.WriteString(segment.Separator)
 		} else {
 			if idx >= len(row)-1 {
-				if !p.hasRightModules() || p.supportsRightModules() {
+				if fill {
+					separatorBackground = p.bgColor(p.theme.FillBg)
+				} else if !p.hasRightModules() || p.supportsRightModules() {
 					separatorBackground = p.reset
 				} else if p.hasRightModules() && rowNum >= len(p.Segments)-1 {
 					nextSegment := p.rightPowerline.Segments[0][0]
@@ -368,12 +647,15 @@ func (p *powerline) drawRow(rowNum int, buffer *bytes.Buffer) {
 		if !p.cfg.Condensed {
 			buffer.WriteRune(' ')
 		}
+		attributesOn, attributesOff := p.attributes(segment)
+		buffer.WriteString(attributesOn)
 		buffer.WriteString(segment.Content)
+		buffer.WriteString(attributesOff)
 		numEastAsianRunes += p.numEastAsianRunes(&segment.Content)
 		if !p.cfg.Condensed {
 			buffer.WriteRune(' ')
 		}
-		if !p.isRightPrompt() {
+		if !leftPointing {
 			buffer.WriteString(separatorBackground)
 			buffer.WriteString(p.fgColor(segment.SeparatorForeground))
 			buffer.WriteString(segment.Separator)
@@ -381,8 +663,18 @@ func (p *powerline) drawRow(rowNum int, buffer *bytes.Buffer) {
 		buffer.WriteString(p.reset)
 	}
 
+	if fill {
+		if rowNum == len(p.Segments)-1 && !p.cfg.PromptOnNewLine {
+			// shells only show the right prompt with a column left for the
+			// cursor and one between it and the left prompt
+			rowWidth += p.rightPromptWidth() + 2
+		}
+		p.drawFill(buffer, rowWidth+numEastAsianRunes)
+		return
+	}
+
 	// Append padding before cursor for left-aligned prompts
-	if !p.isRightPrompt() || !p.hasRightModules() {
+	if !p.isRightPrompt() || (!p.hasRightModules() && p.cfg.Side != "right") {
 		buffer.WriteRune(' ')
 	}
 
@@ -408,14 +700,18 @@ func (p *powerline) draw() string {
 
 	for rowNum := range p.Segments {
 		p.truncateRow(rowNum)
+		if p.cfg.Reverse {
+			p.reverseRow(rowNum)
+		}
 		p.drawRow(rowNum, &buffer)
 		if rowNum < len(p.Segments)-1 {
 			buffer.WriteRune('\n')
 		}
 	}
 
-	if p.cfg.PromptOnNewLine {
+	if p.cfg.PromptOnNewLine && p.align == alignLeft {
 		buffer.WriteRune('\n')
+		buffer.WriteString(escapeVariables(p, p.cfg.MultilineContinuation))
 
 		var foreground, background uint8
 		if p.cfg.PrevError == 0 || p.cfg.StaticPromptIndicator {
@@ -460,6 +756,30 @@ func (p *powerline) draw() string {
 	return buffer.String()
 }
 
+// drawJSON returns the final segments as a JSON list, in the same format
+// plugins use. Rows are separated by NewLine segments, right-aligned segments
+// follow the left-aligned ones.
+func (p *powerline) drawJSON() (string, error) {
+	segments := p.finalSegments()
+	if p.rightPowerline != nil {
+		segments = append(segments, p.rightPowerline.finalSegments()...)
+	}
+	data, err := json.Marshal(segments)
+	return string(data), err
+}
+
+func (p *powerline) finalSegments() []pwl.Segment {
+	segments := []pwl.Segment{}
+	for rowNum := range p.Segments {
+		p.truncateRow(rowNum)
+		if rowNum > 0 {
+			segments = append(segments, pwl.Segment{NewLine: true})
+		}
+		segments = append(segments, p.Segments[rowNum]...)
+	}
+	return segments
+}
+
 func (p *powerline) hasRightModules() bool {
 	return p.rightPowerline != nil && len(p.rightPowerline.Segments[0]) > 0
 }
@@ -469,5 +789,5 @@ func (p *powerline) supportsRightModules() bool {
 }
 
 func (p *powerline) isRightPrompt() bool {
-	return p.align == alignRight && p.supportsRightModules()
+	return p.align == alignRight && (p.supportsRightModules() || p.cfg.Side == "right")
 }
@@ -1,6 +1,8 @@
 package powerline
 
 import (
+	"strings"
+
 	runewidth "github.com/mattn/go-runewidth"
 )
 
@@ -24,11 +26,59 @@ type Segment struct {
 	Width          int
 	// NewLine defines a newline segment to break the powerline in multi lines
 	NewLine bool
+	// Bold, Faint, Italic and Underline set text attributes for Content, if the terminal supports them
+	Bold      bool
+	Faint     bool
+	Italic    bool
+	Underline bool
+}
+
+// Attributes returns the SGR parameters for the text attributes of the segment, separated by ';'
+func (s Segment) Attributes() string {
+	var attributes []string
+	if s.Bold {
+		attributes = append(attributes, "1")
+	}
+	if s.Faint {
+		attributes = append(attributes, "2")
+	}
+	if s.Italic {
+		attributes = append(attributes, "3")
+	}
+	if s.Underline {
+		attributes = append(attributes, "4")
+	}
+	return strings.Join(attributes, ";")
 }
 
 func (s Segment) ComputeWidth(condensed bool) int {
 	if condensed {
-		return runewidth.StringWidth(s.Content) + runewidth.StringWidth(s.Separator)
+		return StringWidth(s.Content) + StringWidth(s.Separator)
+	}
+	return StringWidth(s.Content) + StringWidth(s.Separator) + 2
+}
+
+// StringWidth returns the number of terminal columns s takes up, counting
+// wide characters such as CJK ideographs and emoji as two columns
+func StringWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// TruncateLeft shortens s to at most width columns by dropping characters
+// from its start and prepending prefix
+func TruncateLeft(s string, width int, prefix string) string {
+	if StringWidth(s) <= width {
+		return s
+	}
+	width -= StringWidth(prefix)
+	runes := []rune(s)
+	start := len(runes)
+	for used := 0; start > 0; start-- {
+		w := runewidth.RuneWidth(runes[start-1])
+		if used+w > width {
+			break
+		}
+		used += w
 	}
-	return runewidth.StringWidth(s.Content) + runewidth.StringWidth(s.Separator) + 2
+	return prefix + string(runes[start:])
 }
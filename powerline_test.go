@@ -1,6 +1,26 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+// drawTestConfig returns the defaults with a bare shell, ascii symbols and 256
+// colors, so drawn prompts don't depend on the terminal running the tests
+func drawTestConfig() Config {
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.Mode = "ascii"
+	cfg.ColorMode = "256"
+	return cfg
+}
 
 func Test_detectShell(t *testing.T) {
 	tests := []struct {
@@ -32,3 +52,413 @@ func Test_detectShell(t *testing.T) {
 		})
 	}
 }
+
+func Test_segmentPriorities(t *testing.T) {
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.Priority = []string{"cwd", "git-branch", "user"}
+	cfg.SegmentPriorities = PriorityMap{"user": 10}
+	cfg.Modules = []string{}
+	p := newPowerline(cfg, "/", alignLeft)
+
+	p.appendSegment("git-branch", pwl.Segment{Name: "git-branch", Content: "main"})
+	p.appendSegment("user", pwl.Segment{Name: "user", Content: "me"})
+
+	if got := p.Segments[0][0].Priority; got != 2 {
+		t.Errorf("git-branch priority = %d, want 2", got)
+	}
+	if got := p.Segments[0][1].Priority; got != 10 {
+		t.Errorf("user priority = %d, want 10", got)
+	}
+}
+
+func Test_expandPlaceholders(t *testing.T) {
+	resolved := map[string]bool{}
+	values := map[string]func() string{}
+	for name, value := range map[string]string{"short": "1a2b3c4", "full": "1a2b3c4d5e6f", "tag": "v1.0"} {
+		name, value := name, value
+		values[name] = func() string {
+			resolved[name] = true
+			return value
+		}
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{short}", "1a2b3c4"},
+		{"{short} ({tag})", "1a2b3c4 (v1.0)"},
+		{"{full}", "1a2b3c4d5e6f"},
+		{"{unknown}", "{unknown}"},
+	}
+	for _, tt := range tests {
+		if got := expandPlaceholders(tt.format, values); got != tt.want {
+			t.Errorf("expandPlaceholders(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	resolved = map[string]bool{}
+	expandPlaceholders("{short}", values)
+	if resolved["tag"] || resolved["full"] {
+		t.Errorf("expandPlaceholders resolved unused placeholders: %v", resolved)
+	}
+}
+
+func Test_drawJSON(t *testing.T) {
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.Modules = []string{}
+	p := newPowerline(cfg, "/", alignLeft)
+	p.appendSegment("git-branch", pwl.Segment{Name: "git-branch", Content: "main", Foreground: 0, Background: 148})
+	p.appendSegment("git-status", pwl.Segment{Name: "git-status", Content: "2+", Foreground: 15, Background: 52})
+
+	out, err := p.drawJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var segments []pwl.Segment
+	if err := json.Unmarshal([]byte(out), &segments); err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segments))
+	}
+	if segments[0].Content != "main" || segments[1].Content != "2+" || segments[1].Background != 52 {
+		t.Errorf("unexpected segments %+v", segments)
+	}
+}
+
+func Test_nerdFontSymbols(t *testing.T) {
+	for _, version := range []int{2, 3} {
+		cfg := defaults
+		cfg.Shell = "bare"
+		cfg.Modules = []string{}
+		cfg.NerdFontVersion = version
+		p := newPowerline(cfg, "/", alignLeft)
+
+		symbols := reflect.ValueOf(p.symbols)
+		for i := 0; i < symbols.NumField(); i++ {
+			if symbols.Field(i).String() == "" {
+				t.Errorf("Nerd Font v%d symbol %s is empty", version, symbols.Type().Field(i).Name)
+			}
+		}
+	}
+}
+
+func Test_segmentMinWidths(t *testing.T) {
+	columns, found := os.LookupEnv("COLUMNS")
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("COLUMNS", columns)
+		} else {
+			os.Unsetenv("COLUMNS")
+		}
+	})
+
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.Modules = []string{"host", "user"}
+	cfg.SegmentMinWidths = WidthMap{"host": 80}
+
+	tests := []struct {
+		columns string
+		want    []string
+	}{
+		{"40", []string{"user"}},
+		{"80", []string{"host", "user"}},
+		{"120", []string{"host", "user"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.columns, func(t *testing.T) {
+			os.Setenv("COLUMNS", tt.columns)
+			p := newPowerline(cfg, "/", alignLeft)
+			var got []string
+			for _, segment := range p.Segments[0] {
+				got = append(got, segment.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("segments = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_drawReverse(t *testing.T) {
+	fg := func(code int) string { return fmt.Sprintf("\x1b[38;5;%dm", code) }
+	bg := func(code int) string { return fmt.Sprintf("\x1b[48;5;%dm", code) }
+	reset := "\x1b[0m"
+
+	tests := []struct {
+		reverse bool
+		want    string
+	}{
+		{false, fg(1) + bg(2) + " a " + bg(4) + fg(2) + ">" + reset +
+			fg(3) + bg(4) + " b " + bg(6) + fg(4) + ">" + reset +
+			fg(5) + bg(6) + " c " + reset + fg(6) + ">" + reset + " "},
+		{true, reset + fg(6) + "<" + fg(5) + bg(6) + " c " + reset +
+			bg(6) + fg(4) + "<" + fg(3) + bg(4) + " b " + reset +
+			bg(4) + fg(2) + "<" + fg(1) + bg(2) + " a " + reset + " "},
+	}
+	for _, tt := range tests {
+		cfg := drawTestConfig()
+		cfg.Modules = []string{}
+		cfg.Reverse = tt.reverse
+		p := newPowerline(cfg, "/", alignLeft)
+		p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2})
+		p.appendSegment("b", pwl.Segment{Name: "b", Content: "b", Foreground: 3, Background: 4})
+		p.appendSegment("c", pwl.Segment{Name: "c", Content: "c", Foreground: 5, Background: 6})
+
+		if got := p.draw(); got != tt.want {
+			t.Errorf("draw() with reverse %v = %q, want %q", tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func Test_drawRightSide(t *testing.T) {
+	fg := func(code int) string { return fmt.Sprintf("\x1b[38;5;%dm", code) }
+	bg := func(code int) string { return fmt.Sprintf("\x1b[48;5;%dm", code) }
+	reset := "\x1b[0m"
+
+	cfg := drawTestConfig()
+	cfg.Side = "right"
+	cfg.Modules = []string{"root"}
+	cfg.ModulesRight = []string{}
+	p := newPowerline(cfg, "/", alignRight)
+	p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2})
+	p.appendSegment("b", pwl.Segment{Name: "b", Content: "b", Foreground: 3, Background: 4})
+
+	want := " " + reset + fg(2) + "<" + fg(1) + bg(2) + " a " + reset +
+		bg(2) + fg(4) + "<" + fg(3) + bg(4) + " b " + reset
+	if got := p.draw(); got != want {
+		t.Errorf("draw() = %q, want %q", got, want)
+	}
+}
+
+func Test_drawEmptySegment(t *testing.T) {
+	fg := func(code int) string { return fmt.Sprintf("\x1b[38;5;%dm", code) }
+	bg := func(code int) string { return fmt.Sprintf("\x1b[48;5;%dm", code) }
+	reset := "\x1b[0m"
+
+	cfg := drawTestConfig()
+	cfg.Modules = []string{}
+	p := newPowerline(cfg, "/", alignLeft)
+	p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2})
+	p.appendSegment("b", pwl.Segment{Name: "b", Content: " ", Foreground: 3, Background: 4})
+	p.appendSegment("c", pwl.Segment{Name: "c", Content: "c", Foreground: 5, Background: 6})
+
+	want := fg(1) + bg(2) + " a " + bg(6) + fg(2) + ">" + reset +
+		fg(5) + bg(6) + " c " + reset + fg(6) + ">" + reset + " "
+	if got := p.draw(); got != want {
+		t.Errorf("draw() with an empty segment = %q, want %q", got, want)
+	}
+}
+
+func Test_drawFillToWidth(t *testing.T) {
+	columns, found := os.LookupEnv("COLUMNS")
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("COLUMNS", columns)
+		} else {
+			os.Unsetenv("COLUMNS")
+		}
+	})
+	os.Setenv("COLUMNS", "12")
+
+	fg := func(code uint8) string { return fmt.Sprintf("\x1b[38;5;%dm", code) }
+	bg := func(code uint8) string { return fmt.Sprintf("\x1b[48;5;%dm", code) }
+	reset := "\x1b[0m"
+
+	cfg := drawTestConfig()
+	cfg.Modules = []string{}
+	cfg.FillToWidth = true
+	cfg.FillChar = "-"
+	p := newPowerline(cfg, "/", alignLeft)
+	p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2})
+	p.appendSegment("b", pwl.Segment{Name: "b", Content: "b", Foreground: 3, Background: 4})
+	p.appendSegment("newline", pwl.Segment{NewLine: true})
+	p.appendSegment("c", pwl.Segment{Name: "c", Content: "c", Foreground: 5, Background: 6})
+
+	// the line the cursor ends up on is left alone
+	want := fg(1) + bg(2) + " a " + bg(4) + fg(2) + ">" + reset +
+		fg(3) + bg(4) + " b " + bg(p.theme.FillBg) + fg(4) + ">" + reset +
+		fg(p.theme.FillFg) + bg(p.theme.FillBg) + "----" + reset + "\n" +
+		fg(5) + bg(6) + " c " + reset + fg(6) + ">" + reset + " "
+	if got := p.draw(); got != want {
+		t.Errorf("draw() filled to 12 columns = %q, want %q", got, want)
+	}
+
+	// with a right prompt, the cursor line is filled up to it
+	os.Setenv("COLUMNS", "20")
+	cfg.Shell = "zsh"
+	cfg.Eval = true
+	p = newPowerline(cfg, "/", alignLeft)
+	p.rightPowerline = newPowerline(cfg, "/", alignRight)
+	p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2})
+	p.rightPowerline.appendSegment("r", pwl.Segment{Name: "r", Content: "r", Foreground: 3, Background: 4})
+	var buffer bytes.Buffer
+	p.drawRow(0, &buffer)
+	// 20 columns less 4 for the left prompt, 5 for the right one and 2 spare
+	if got := buffer.String(); !strings.HasSuffix(got, strings.Repeat("-", 9)+p.reset) || strings.Contains(got, strings.Repeat("-", 10)) {
+		t.Errorf("drawRow() next to a right prompt = %q, want it filled with 9 columns", got)
+	}
+}
+
+func Test_drawMultilineContinuation(t *testing.T) {
+	fg := func(code int) string { return fmt.Sprintf("\x1b[38;5;%dm", code) }
+	bg := func(code int) string { return fmt.Sprintf("\x1b[48;5;%dm", code) }
+	reset := "\x1b[0m"
+
+	tests := []struct {
+		continuation string
+		want         string
+	}{
+		{"", fg(1) + bg(2) + " a " + reset + fg(2) + ">" + reset + " \n" +
+			fg(3) + bg(4) + " b " + reset + fg(4) + ">" + reset + " "},
+		{"\u2514 ", fg(1) + bg(2) + " a " + reset + fg(2) + ">" + reset + " \n" +
+			"\u2514 " + fg(3) + bg(4) + " b " + reset + fg(4) + ">" + reset + " "},
+	}
+	for _, tt := range tests {
+		cfg := drawTestConfig()
+		cfg.Modules = []string{}
+		cfg.MultilineContinuation = tt.continuation
+		p := newPowerline(cfg, "/", alignLeft)
+		p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2})
+		p.appendSegment("newline", pwl.Segment{NewLine: true})
+		p.appendSegment("b", pwl.Segment{Name: "b", Content: "b", Foreground: 3, Background: 4})
+
+		if got := p.draw(); got != tt.want {
+			t.Errorf("draw() with continuation %q = %q, want %q", tt.continuation, got, tt.want)
+		}
+	}
+}
+
+func Test_drawGroup(t *testing.T) {
+	fg := func(code uint8) string { return fmt.Sprintf("\x1b[38;5;%dm", code) }
+	bg := func(code uint8) string { return fmt.Sprintf("\x1b[48;5;%dm", code) }
+	reset := "\x1b[0m"
+
+	segments := []pwl.Segment{
+		{Name: "a", Content: "a", Foreground: 1, Background: 2},
+		{Name: "b", Content: "b", Foreground: 3, Background: 4},
+		{Name: "c", Content: "c", Foreground: 5, Background: 6},
+	}
+	cfg := drawTestConfig()
+	cfg.Modules = []string{}
+
+	p := newPowerline(cfg, "/", alignLeft)
+	for _, segment := range segments {
+		p.appendSegment(segment.Name, segment)
+	}
+	separate := fg(1) + bg(2) + " a " + bg(4) + fg(2) + ">" + reset +
+		fg(3) + bg(4) + " b " + bg(6) + fg(4) + ">" + reset +
+		fg(5) + bg(6) + " c " + reset + fg(6) + ">" + reset + " "
+	if got := p.draw(); got != separate {
+		t.Errorf("draw() without a group = %q, want %q", got, separate)
+	}
+
+	p = newPowerline(cfg, "/", alignLeft)
+	for _, segment := range p.groupSegments(segments) {
+		p.appendSegment(segment.Name, segment)
+	}
+	thin := bg(2) + fg(p.theme.SeparatorFg) + ">" + reset
+	grouped := fg(1) + bg(2) + " a " + thin +
+		fg(3) + bg(2) + " b " + thin +
+		fg(5) + bg(2) + " c " + reset + fg(2) + ">" + reset + " "
+	if got := p.draw(); got != grouped {
+		t.Errorf("draw() with a group = %q, want %q", got, grouped)
+	}
+}
+
+func Test_drawAttributes(t *testing.T) {
+	fg := func(code int) string { return fmt.Sprintf("\x1b[38;5;%dm", code) }
+	bg := func(code int) string { return fmt.Sprintf("\x1b[48;5;%dm", code) }
+	reset := "\x1b[0m"
+
+	tests := []struct {
+		name    string
+		segment pwl.Segment
+		want    string
+	}{
+		{"none", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2},
+			fg(1) + bg(2) + " a " + reset + fg(2) + ">" + reset + " "},
+		{"faint", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2, Faint: true},
+			fg(1) + bg(2) + " \x1b[2ma\x1b[22;23;24m " + reset + fg(2) + ">" + reset + " "},
+		{"bold italic underline", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2, Bold: true, Italic: true, Underline: true},
+			fg(1) + bg(2) + " \x1b[1;3;4ma\x1b[22;23;24m " + reset + fg(2) + ">" + reset + " "},
+	}
+	for _, tt := range tests {
+		cfg := drawTestConfig()
+		cfg.Modules = []string{}
+		p := newPowerline(cfg, "/", alignLeft)
+		p.appendSegment("a", tt.segment)
+
+		if got := p.draw(); got != tt.want {
+			t.Errorf("draw() with %s attributes = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_drawSeparatorOverride(t *testing.T) {
+	fg := func(code int) string { return fmt.Sprintf("\x1b[38;5;%dm", code) }
+	bg := func(code int) string { return fmt.Sprintf("\x1b[48;5;%dm", code) }
+	reset := "\x1b[0m"
+
+	tests := []struct {
+		override bool
+		want     string
+	}{
+		{false, fg(1) + bg(2) + " a " + bg(4) + fg(2) + ">" + reset +
+			fg(3) + bg(4) + " b " + reset + fg(4) + ">" + reset + " "},
+		{true, fg(1) + bg(2) + " a " + bg(4) + fg(9) + ">" + reset +
+			fg(3) + bg(4) + " b " + reset + fg(9) + ">" + reset + " "},
+	}
+	for _, tt := range tests {
+		cfg := drawTestConfig()
+		cfg.Modules = []string{}
+		p := newPowerline(cfg, "/", alignLeft)
+		p.theme.SeparatorOverride = tt.override
+		p.theme.SeparatorOverrideFg = 9
+		p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2})
+		p.appendSegment("b", pwl.Segment{Name: "b", Content: "b", Foreground: 3, Background: 4})
+
+		if got := p.draw(); got != tt.want {
+			t.Errorf("draw() with separator override %v = %q, want %q", tt.override, got, tt.want)
+		}
+	}
+}
+
+func Test_dumbTerminal(t *testing.T) {
+	for _, name := range []string{"TERM", "INSIDE_EMACS"} {
+		value, found := os.LookupEnv(name)
+		name := name
+		t.Cleanup(func() {
+			if found {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+	os.Setenv("TERM", "dumb")
+	os.Unsetenv("INSIDE_EMACS")
+
+	cfg := defaults
+	cfg.Shell = "autodetect"
+	cfg.Modules = []string{}
+	p := newPowerline(cfg, "/", alignLeft)
+	p.appendSegment("a", pwl.Segment{Name: "a", Content: "a", Foreground: 1, Background: 2})
+
+	want := "\x1b[38;5;1m\x1b[48;5;2m a \x1b[0m\x1b[38;5;2m▶\x1b[0m "
+	if got := p.draw(); got != want {
+		t.Errorf("draw() on a dumb terminal = %q, want %q", got, want)
+	}
+
+	// an explicit -mode is kept, even if it is the default one
+	cfg.ModeSet = true
+	p = newPowerline(cfg, "/", alignLeft)
+	if p.symbols.Separator != cfg.Modes[defaults.Mode].Separator {
+		t.Errorf("newPowerline() on a dumb terminal with -mode %s uses separator %q, want %q",
+			defaults.Mode, p.symbols.Separator, cfg.Modes[defaults.Mode].Separator)
+	}
+}
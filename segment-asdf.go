@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+const asdfToolVersionsFile = ".tool-versions"
+
+type asdfToolVersion struct {
+	tool    string
+	version string
+}
+
+// find the closest .tool-versions file, walking up from dir
+func findAsdfToolVersions(dir string) (*os.File, error) {
+	for {
+		file, err := os.Open(filepath.Join(dir, asdfToolVersionsFile))
+		if err == nil {
+			return file, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, err
+		}
+		dir = parent
+	}
+}
+
+func readAsdfToolVersions(dir string) []asdfToolVersion {
+	file, err := findAsdfToolVersions(dir)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var versions []asdfToolVersion
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// only the first version is used, the others are fallbacks
+		versions = append(versions, asdfToolVersion{tool: fields[0], version: fields[1]})
+	}
+	return versions
+}
+
+func segmentAsdf(p *powerline) []pwl.Segment {
+	versions := readAsdfToolVersions(p.cwd)
+	if len(versions) == 0 {
+		return []pwl.Segment{}
+	}
+
+	wanted := make(map[string]bool)
+	for _, tool := range p.cfg.AsdfTools {
+		if tool != "" {
+			wanted[tool] = true
+		}
+	}
+
+	segments := []pwl.Segment{}
+	for _, version := range versions {
+		if len(wanted) > 0 && !wanted[version.tool] {
+			continue
+		}
+		segments = append(segments, pwl.Segment{
+			Name:                "asdf",
+			Content:             escapeVariables(p, version.tool+" "+version.version),
+			Foreground:          p.theme.AsdfFg,
+			Background:          p.theme.AsdfBg,
+			Separator:           p.symbols.SeparatorThin,
+			SeparatorForeground: p.theme.AsdfFg,
+		})
+	}
+	if len(segments) > 0 {
+		segments[len(segments)-1].Separator = ""
+		segments[len(segments)-1].SeparatorForeground = 0
+	}
+	return segments
+}
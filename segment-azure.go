@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+type azureProfile struct {
+	Subscriptions []struct {
+		Name      string `json:"name"`
+		IsDefault bool   `json:"isDefault"`
+	} `json:"subscriptions"`
+}
+
+// getAzureConfigDir returns where the az CLI keeps its configuration
+func getAzureConfigDir() (string, error) {
+	if dir := os.Getenv("AZURE_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".azure"), nil
+}
+
+// getAzureSubscription returns the name of the default subscription in the
+// az CLI profile
+func getAzureSubscription() (string, error) {
+	configDir, err := getAzureConfigDir()
+	if err != nil {
+		return "", err
+	}
+	content, err := ioutil.ReadFile(filepath.Join(configDir, "azureProfile.json"))
+	if err != nil {
+		return "", err
+	}
+	// az writes the profile with a byte order mark, which encoding/json rejects
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	var profile azureProfile
+	if err := json.Unmarshal(content, &profile); err != nil {
+		return "", err
+	}
+	for _, subscription := range profile.Subscriptions {
+		if subscription.IsDefault {
+			return subscription.Name, nil
+		}
+	}
+	return "", nil
+}
+
+func segmentAzure(p *powerline) []pwl.Segment {
+	subscription, err := getAzureSubscription()
+	if err != nil || subscription == "" {
+		return []pwl.Segment{}
+	}
+	return []pwl.Segment{{
+		Name:       "azure",
+		Content:    p.symbols.Azure + " " + escapeVariables(p, subscription),
+		Foreground: p.theme.AzureFg,
+		Background: p.theme.AzureBg,
+	}}
+}
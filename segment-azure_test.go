@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_segmentAzure(t *testing.T) {
+	value, found := os.LookupEnv("AZURE_CONFIG_DIR")
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("AZURE_CONFIG_DIR", value)
+		} else {
+			os.Unsetenv("AZURE_CONFIG_DIR")
+		}
+	})
+	dir := t.TempDir()
+	os.Setenv("AZURE_CONFIG_DIR", dir)
+
+	cfg := defaults
+	cfg.Shell = "bare"
+	p := testPowerline(cfg)
+	p.shell = cfg.Shells[cfg.Shell]
+	if segments := segmentAzure(p); len(segments) != 0 {
+		t.Errorf("segmentAzure() without a profile = %+v, want none", segments)
+	}
+
+	profile := "\xef\xbb\xbf" + `{"installationId": "x", "subscriptions": [
+		{"id": "1", "name": "Development", "isDefault": false},
+		{"id": "2", "name": "Production", "isDefault": true}
+	]}`
+	ioutil.WriteFile(filepath.Join(dir, "azureProfile.json"), []byte(profile), 0644)
+	segments := segmentAzure(p)
+	if want := p.symbols.Azure + " Production"; len(segments) != 1 || segments[0].Content != want {
+		t.Errorf("segmentAzure() = %+v, want %q", segments, want)
+	}
+}
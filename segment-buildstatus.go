@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+// buildStatusPath resolves the configured build status file against the root
+// of the repository, or the current directory outside of one
+func buildStatusPath(p *powerline) string {
+	if filepath.IsAbs(p.cfg.BuildStatusFile) {
+		return p.cfg.BuildStatusFile
+	}
+	root, err := repoRoot(p.cwd)
+	if err != nil {
+		root = p.cwd
+	}
+	return filepath.Join(root, p.cfg.BuildStatusFile)
+}
+
+func segmentBuildStatus(p *powerline) []pwl.Segment {
+	if p.cfg.BuildStatusFile == "" {
+		return []pwl.Segment{}
+	}
+	status, err := ioutil.ReadFile(buildStatusPath(p))
+	if err != nil {
+		return []pwl.Segment{}
+	}
+
+	switch strings.TrimSpace(string(status)) {
+	case "ok":
+		return []pwl.Segment{{
+			Name:       "build-status",
+			Content:    p.symbols.BuildOk,
+			Foreground: p.theme.CmdPassedFg,
+			Background: p.theme.CmdPassedBg,
+		}}
+	case "fail":
+		return []pwl.Segment{{
+			Name:       "build-status",
+			Content:    p.symbols.BuildFailed,
+			Foreground: p.theme.CmdFailedFg,
+			Background: p.theme.CmdFailedBg,
+		}}
+	}
+	return []pwl.Segment{}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func Test_segmentBuildStatus(t *testing.T) {
+	cfg := defaults
+	cfg.BuildStatusFile = filepath.Join(t.TempDir(), ".build-status")
+	p := testPowerline(cfg)
+
+	if segments := segmentBuildStatus(p); len(segments) != 0 {
+		t.Errorf("segmentBuildStatus() without a status file = %+v, want none", segments)
+	}
+
+	tests := []struct {
+		status string
+		want   string
+		bg     uint8
+	}{
+		{"ok\n", p.symbols.BuildOk, p.theme.CmdPassedBg},
+		{"fail", p.symbols.BuildFailed, p.theme.CmdFailedBg},
+		{"running", "", 0},
+	}
+	for _, tt := range tests {
+		ioutil.WriteFile(cfg.BuildStatusFile, []byte(tt.status), 0644)
+		segments := segmentBuildStatus(p)
+		if tt.want == "" {
+			if len(segments) != 0 {
+				t.Errorf("segmentBuildStatus() for %q = %+v, want none", tt.status, segments)
+			}
+			continue
+		}
+		if len(segments) != 1 || segments[0].Content != tt.want || segments[0].Background != tt.bg {
+			t.Errorf("segmentBuildStatus() for %q = %+v, want %q on %d", tt.status, segments, tt.want, tt.bg)
+		}
+	}
+}
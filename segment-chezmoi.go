@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+const (
+	chezmoiRootFile = ".chezmoiroot"
+	chezmoiTimeout  = 500 * time.Millisecond
+)
+
+// insideChezmoiSource reports whether dir or one of its parents is a chezmoi
+// source directory
+func insideChezmoiSource(dir string) bool {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, chezmoiRootFile)); err == nil {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// countChezmoiChanges counts the managed files listed by chezmoi status
+func countChezmoiChanges(out string) int {
+	count := 0
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+func segmentChezmoi(p *powerline) []pwl.Segment {
+	if os.Getenv("CHEZMOI") == "" && !insideChezmoiSource(p.cwd) {
+		return []pwl.Segment{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chezmoiTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "chezmoi", "status").Output()
+	if err != nil {
+		return []pwl.Segment{}
+	}
+
+	content := "chezmoi"
+	foreground, background := p.theme.RepoCleanFg, p.theme.RepoCleanBg
+	if changes := countChezmoiChanges(string(out)); changes > 0 {
+		content = fmt.Sprintf("%s %d", content, changes)
+		foreground, background = p.theme.RepoDirtyFg, p.theme.RepoDirtyBg
+	}
+	return []pwl.Segment{{
+		Name:       "chezmoi",
+		Content:    content,
+		Foreground: foreground,
+		Background: background,
+	}}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_countChezmoiChanges(t *testing.T) {
+	tests := []struct {
+		out  string
+		want int
+	}{
+		{"", 0},
+		{"\n", 0},
+		{" M .bashrc\n", 1},
+		{" M .bashrc\nA  .config/git/config\n R .local/bin/run\n", 3},
+	}
+	for _, tt := range tests {
+		if got := countChezmoiChanges(tt.out); got != tt.want {
+			t.Errorf("countChezmoiChanges(%q) = %d, want %d", tt.out, got, tt.want)
+		}
+	}
+}
+
+func Test_insideChezmoiSource(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "dot_config", "git")
+	os.MkdirAll(nested, 0755)
+
+	if insideChezmoiSource(nested) {
+		t.Errorf("insideChezmoiSource() without %s = true, want false", chezmoiRootFile)
+	}
+	ioutil.WriteFile(filepath.Join(root, chezmoiRootFile), []byte("home\n"), 0644)
+	if !insideChezmoiSource(nested) {
+		t.Errorf("insideChezmoiSource() below %s = false, want true", chezmoiRootFile)
+	}
+}
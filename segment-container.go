@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+// readContainerEnv returns the container name podman writes to
+// /run/.containerenv, and whether the file exists at all
+func readContainerEnv(path string) (string, bool) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "name=") {
+			return strings.Trim(strings.TrimPrefix(line, "name="), `"`), true
+		}
+	}
+	return "", true
+}
+
+// containerName returns the name of the container the shell runs in, as set
+// up by podman, toolbox, distrobox or flatpak. root is prepended to the
+// marker files so tests can fake them.
+func containerName(root string) (string, bool) {
+	name, containerEnv := readContainerEnv(filepath.Join(root, "run", ".containerenv"))
+	if name != "" {
+		return name, true
+	}
+	if id := os.Getenv("CONTAINER_ID"); id != "" {
+		return id, true
+	}
+	if os.Getenv("DISTROBOX_ENTER_PATH") != "" {
+		return "distrobox", true
+	}
+	if pathExists(filepath.Join(root, "run", ".toolboxenv")) {
+		return "toolbox", true
+	}
+	if id := os.Getenv("FLATPAK_ID"); id != "" {
+		return id, true
+	}
+	if kind := os.Getenv("container"); kind != "" {
+		return kind, true
+	}
+	if containerEnv {
+		return "container", true
+	}
+	return "", false
+}
+
+func segmentContainer(p *powerline) []pwl.Segment {
+	name, ok := containerName("/")
+	if !ok {
+		return []pwl.Segment{}
+	}
+
+	return []pwl.Segment{{
+		Name:       "container",
+		Content:    p.symbols.Container + " " + escapeEvalSafe(p, name),
+		Foreground: p.theme.ContainerFg,
+		Background: p.theme.ContainerBg,
+	}}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_containerName(t *testing.T) {
+	vars := []string{"CONTAINER_ID", "DISTROBOX_ENTER_PATH", "FLATPAK_ID", "container"}
+	for _, name := range vars {
+		value, found := os.LookupEnv(name)
+		name := name
+		t.Cleanup(func() {
+			if found {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+		os.Unsetenv(name)
+	}
+
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, "run"), 0755)
+	if name, ok := containerName(root); ok {
+		t.Errorf("containerName() on the host = %q, want none", name)
+	}
+
+	os.Setenv("container", "oci")
+	if name, ok := containerName(root); !ok || name != "oci" {
+		t.Errorf("containerName() with $container = %q, %v, want oci", name, ok)
+	}
+
+	ioutil.WriteFile(filepath.Join(root, "run", ".toolboxenv"), []byte{}, 0644)
+	if name, ok := containerName(root); !ok || name != "toolbox" {
+		t.Errorf("containerName() in toolbox = %q, %v, want toolbox", name, ok)
+	}
+
+	os.Setenv("CONTAINER_ID", "fedora-toolbox-39")
+	if name, ok := containerName(root); !ok || name != "fedora-toolbox-39" {
+		t.Errorf("containerName() with $CONTAINER_ID = %q, %v, want fedora-toolbox-39", name, ok)
+	}
+
+	containerEnv := "engine=\"podman-4.9.0\"\nname=\"dev\"\nid=\"0123abcd\"\n"
+	ioutil.WriteFile(filepath.Join(root, "run", ".containerenv"), []byte(containerEnv), 0644)
+	if name, ok := containerName(root); !ok || name != "dev" {
+		t.Errorf("containerName() with .containerenv = %q, %v, want dev", name, ok)
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -101,16 +103,45 @@ Aliases:
 	return pathSegments
 }
 
+// trimHomeDir returns the part of cwd below the home directory, and whether
+// cwd is inside of it at all. Windows paths are compared case-insensitively.
+func trimHomeDir(p *powerline, cwd string) (string, bool) {
+	home := p.userInfo.HomeDir
+	if home == "" {
+		home = os.Getenv(homeEnvName())
+	}
+	home = strings.TrimRight(home, string(os.PathSeparator))
+	if home == "" || len(cwd) < len(home) {
+		return cwd, false
+	}
+
+	prefix, rest := cwd[:len(home)], cwd[len(home):]
+	if rest != "" && !os.IsPathSeparator(rest[0]) {
+		return cwd, false
+	}
+	if prefix == home || (runtime.GOOS == "windows" && strings.EqualFold(prefix, home)) {
+		return rest, true
+	}
+	return cwd, false
+}
+
 func cwdToPathSegments(p *powerline, cwd string) []pathSegment {
 	pathSeparator := string(os.PathSeparator)
 	pathSegments := make([]pathSegment, 0)
 
-	if strings.HasPrefix(cwd, p.userInfo.HomeDir) {
+	if rest, inHome := trimHomeDir(p, cwd); inHome {
 		pathSegments = append(pathSegments, pathSegment{
 			path: "~",
 			home: true,
 		})
-		cwd = cwd[len(p.userInfo.HomeDir):]
+		cwd = rest
+	} else if volume := filepath.VolumeName(cwd); volume != "" {
+		// drive letters and UNC shares on Windows
+		pathSegments = append(pathSegments, pathSegment{
+			path: volume,
+			root: true,
+		})
+		cwd = cwd[len(volume):]
 	} else if cwd == pathSeparator {
 		pathSegments = append(pathSegments, pathSegment{
 			path: pathSeparator,
@@ -134,19 +165,161 @@ func cwdToPathSegments(p *powerline, cwd string) []pathSegment {
 }
 
 func maybeShortenName(p *powerline, pathSegment string) string {
-	if p.cfg.CwdMaxDirSize > 0 && len(pathSegment) > p.cfg.CwdMaxDirSize {
-		return pathSegment[:p.cfg.CwdMaxDirSize]
+	if p.cfg.CwdMaxDirSize > 0 {
+		if runes := []rune(pathSegment); len(runes) > p.cfg.CwdMaxDirSize {
+			return string(runes[:p.cfg.CwdMaxDirSize])
+		}
 	}
 	return pathSegment
 }
 
+// headTailPathSegments keeps the first head and last tail path segments,
+// replacing the ones in between with an ellipsis
+func headTailPathSegments(pathSegments []pathSegment, head, tail int) []pathSegment {
+	if head < 0 {
+		head = 0
+	}
+	if tail < 1 {
+		tail = 1
+	}
+	if len(pathSegments) <= head+tail {
+		return pathSegments
+	}
+	shortened := make([]pathSegment, 0, head+tail+1)
+	shortened = append(shortened, pathSegments[:head]...)
+	shortened = append(shortened, pathSegment{
+		path:     ellipsis,
+		ellipsis: true,
+	})
+	return append(shortened, pathSegments[len(pathSegments)-tail:]...)
+}
+
+// cwdWidth returns the number of columns the path segments take up, counting
+// one column for the separator between two of them
+func cwdWidth(pathSegments []pathSegment) int {
+	width := 0
+	for idx, pathSegment := range pathSegments {
+		if idx > 0 {
+			width++
+		}
+		width += pwl.StringWidth(pathSegment.path)
+	}
+	return width
+}
+
+// limitCwdWidth drops path segments from the start, replacing them with an
+// ellipsis, until the path fits in maxWidth columns. A last directory too wide
+// by itself is truncated from the left.
+func limitCwdWidth(pathSegments []pathSegment, maxWidth int) []pathSegment {
+	if maxWidth <= 0 || cwdWidth(pathSegments) <= maxWidth {
+		return pathSegments
+	}
+	ellipsisSegment := pathSegment{
+		path:     ellipsis,
+		ellipsis: true,
+	}
+	// the ellipsis and its separator take up two columns
+	kept := pathSegments
+	for len(kept) > 1 && cwdWidth(kept)+2 > maxWidth {
+		kept = kept[1:]
+	}
+	if cwdWidth(kept)+2 > maxWidth {
+		last := kept[0]
+		last.path = pwl.TruncateLeft(last.path, maxWidth, ellipsis)
+		return []pathSegment{last}
+	}
+	return append([]pathSegment{ellipsisSegment}, kept...)
+}
+
+// uniquePrefix returns the shortest prefix of name not shared by any other
+// entry in dir, or its first character if dir can't be read
+func uniquePrefix(dir, name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 {
+		return name
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return string(runes[:1])
+	}
+	siblings, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return string(runes[:1])
+	}
+
+	for n := 1; n < len(runes); n++ {
+		prefix := string(runes[:n])
+		unique := true
+		for _, sibling := range siblings {
+			if sibling != name && strings.HasPrefix(sibling, prefix) {
+				unique = false
+				break
+			}
+		}
+		if unique {
+			return prefix
+		}
+	}
+	return name
+}
+
+// uniquePrefixPathSegments abbreviates every directory but the current one to
+// its shortest unique prefix among its siblings. Walking stops at the home
+// directory or an alias, which are shown as they are.
+func uniquePrefixPathSegments(cwd string, pathSegments []pathSegment) []pathSegment {
+	shortened := make([]pathSegment, len(pathSegments))
+	copy(shortened, pathSegments)
+
+	dir := filepath.Clean(cwd)
+	for idx := len(shortened) - 1; idx >= 0; idx-- {
+		segment := &shortened[idx]
+		if segment.home || segment.root || segment.alias || segment.ellipsis {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if idx != len(shortened)-1 {
+			segment.path = uniquePrefix(parent, segment.path)
+		}
+		dir = parent
+	}
+	return shortened
+}
+
 func escapeVariables(p *powerline, pathSegment string) string {
 	pathSegment = strings.Replace(pathSegment, `\`, p.shell.EscapedBackslash, -1)
 	pathSegment = strings.Replace(pathSegment, "`", p.shell.EscapedBacktick, -1)
 	pathSegment = strings.Replace(pathSegment, `$`, p.shell.EscapedDollar, -1)
+	if p.cfg.EvalSafe {
+		if p.shell.EscapedPercent != "" {
+			pathSegment = strings.Replace(pathSegment, `%`, p.shell.EscapedPercent, -1)
+		}
+		if p.cfg.Eval {
+			pathSegment = strings.Replace(pathSegment, `"`, `\"`, -1)
+		}
+	}
 	return pathSegment
 }
 
+// escapeEvalSafe escapes content taken from outside sources, such as branch
+// names, when -eval-safe is set
+func escapeEvalSafe(p *powerline, content string) string {
+	if !p.cfg.EvalSafe {
+		return content
+	}
+	return escapeVariables(p, content)
+}
+
+// escapePromptText escapes free text, such as commit messages, for every
+// shell, including the % of zsh prompts without -eval-safe
+func escapePromptText(p *powerline, content string) string {
+	content = escapeVariables(p, content)
+	if !p.cfg.EvalSafe && p.shell.EscapedPercent != "" {
+		content = strings.Replace(content, `%`, p.shell.EscapedPercent, -1)
+	}
+	return content
+}
+
 func getColor(p *powerline, pathSegment pathSegment, isLastDir bool) (uint8, uint8, bool) {
 	if pathSegment.home && p.theme.HomeSpecialDisplay {
 		return p.theme.HomeFg, p.theme.HomeBg, true
@@ -158,13 +331,42 @@ func getColor(p *powerline, pathSegment pathSegment, isLastDir bool) (uint8, uin
 	return p.theme.PathFg, p.theme.PathBg, false
 }
 
+// gradientColor interpolates between the color indices from and to for
+// step idx of n. Steps never reach to, which is left for the current directory.
+func gradientColor(from, to uint8, idx, n int) uint8 {
+	if n < 2 {
+		return from
+	}
+	return uint8(int(from) + (int(to)-int(from))*idx/(n-1))
+}
+
+const cwdDeletedMarker = "(deleted)"
+
 func segmentCwd(p *powerline) (segments []pwl.Segment) {
+	if p.cfg.CwdNativeToken && p.shell.CwdToken != "" {
+		// the shell shows the path with the home directory as ~
+		shown := p.cwd
+		if rest, inHome := trimHomeDir(p, shown); inHome {
+			shown = "~" + rest
+		}
+		return []pwl.Segment{{
+			Name:       "cwd",
+			Content:    p.shell.CwdToken,
+			Foreground: p.theme.CwdFg,
+			Background: p.theme.PathBg,
+			Width:      pwl.StringWidth(shown),
+		}}
+	}
+
 	cwd := p.cwd
 
 	switch p.cfg.CwdMode {
 	case "plain":
-		if strings.HasPrefix(cwd, p.userInfo.HomeDir) {
-			cwd = "~" + cwd[len(p.userInfo.HomeDir):]
+		if rest, inHome := trimHomeDir(p, cwd); inHome {
+			cwd = "~" + rest
+		}
+		if p.cfg.CwdMaxLength > 0 {
+			cwd = pwl.TruncateLeft(cwd, p.cfg.CwdMaxLength, ellipsis)
 		}
 
 		segments = append(segments, pwl.Segment{
@@ -178,6 +380,10 @@ func segmentCwd(p *powerline) (segments []pwl.Segment) {
 
 		if p.cfg.CwdMode == "dironly" {
 			pathSegments = pathSegments[len(pathSegments)-1:]
+		} else if p.cfg.CwdMode == "head-tail" {
+			pathSegments = headTailPathSegments(pathSegments, p.cfg.CwdHeadCount, p.cfg.CwdTailCount)
+		} else if p.cfg.CwdMode == "unique-prefix" {
+			pathSegments = uniquePrefixPathSegments(cwd, pathSegments)
 		} else {
 			maxDepth := p.cfg.CwdMaxDepth
 			if maxDepth <= 0 {
@@ -222,10 +428,14 @@ func segmentCwd(p *powerline) (segments []pwl.Segment) {
 				})
 			}
 		}
+		pathSegments = limitCwdWidth(pathSegments, p.cfg.CwdMaxLength)
 
 		for idx, pathSegment := range pathSegments {
 			isLastDir := idx == len(pathSegments)-1
 			foreground, background, special := getColor(p, pathSegment, isLastDir)
+			if p.cfg.CwdGradient && !special && !isLastDir {
+				foreground = gradientColor(p.theme.CwdGradientStartFg, p.theme.CwdGradientEndFg, idx, len(pathSegments))
+			}
 
 			segment := pwl.Segment{
 				Content:    escapeVariables(p, maybeShortenName(p, pathSegment.path)),
@@ -234,10 +444,10 @@ func segmentCwd(p *powerline) (segments []pwl.Segment) {
 			}
 
 			if !special {
-				if p.align == alignRight && p.supportsRightModules() && idx != 0 {
+				if p.isRightPrompt() && idx != 0 {
 					segment.Separator = p.symbols.SeparatorReverseThin
 					segment.SeparatorForeground = p.theme.SeparatorFg
-				} else if (p.align == alignLeft || !p.supportsRightModules()) && !isLastDir {
+				} else if !p.isRightPrompt() && !isLastDir {
 					segment.Separator = p.symbols.SeparatorThin
 					segment.SeparatorForeground = p.theme.SeparatorFg
 				}
@@ -251,5 +461,18 @@ func segmentCwd(p *powerline) (segments []pwl.Segment) {
 			segments = append(segments, segment)
 		}
 	}
+
+	if p.cwdDeleted {
+		if len(segments) == 0 {
+			segments = append(segments, pwl.Segment{
+				Name:       "cwd",
+				Content:    cwdDeletedMarker,
+				Foreground: p.theme.CwdFg,
+				Background: p.theme.PathBg,
+			})
+		} else {
+			segments[len(segments)-1].Content += " " + cwdDeletedMarker
+		}
+	}
 	return segments
 }
@@ -0,0 +1,251 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_cwdGradient(t *testing.T) {
+	cfg := defaults
+	cfg.CwdGradient = true
+	cfg.CwdMaxDepth = 10
+	p := testPowerline(cfg)
+	p.cwd = "/a/b/c/d/e"
+	p.userInfo = user.User{HomeDir: "/home/user"}
+
+	var foregrounds []uint8
+	for _, segment := range segmentCwd(p) {
+		foregrounds = append(foregrounds, segment.Foreground)
+	}
+	// the current directory always uses the regular cwd color
+	want := []uint8{243, 245, 247, 249, p.theme.CwdFg}
+	if !reflect.DeepEqual(foregrounds, want) {
+		t.Errorf("cwd foregrounds = %v, want %v", foregrounds, want)
+	}
+}
+
+func Test_cwdHomeBoundary(t *testing.T) {
+	p := testPowerline(defaults)
+	p.userInfo = user.User{HomeDir: "/home/user"}
+
+	tests := []struct {
+		cwd  string
+		want []string
+	}{
+		{"/home/user", []string{"~"}},
+		{"/home/user/src", []string{"~", "src"}},
+		{"/home/username/src", []string{"home", "username", "src"}},
+		{"/", []string{"/"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, segment := range cwdToPathSegments(p, tt.cwd) {
+			got = append(got, segment.path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cwdToPathSegments(%q) = %v, want %v", tt.cwd, got, tt.want)
+		}
+	}
+}
+
+func Test_cwdDeleted(t *testing.T) {
+	wd, _ := os.Getwd()
+	pwd, found := os.LookupEnv("PWD")
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		if found {
+			os.Setenv("PWD", pwd)
+		} else {
+			os.Unsetenv("PWD")
+		}
+	})
+
+	dir := filepath.Join(t.TempDir(), "gone")
+	os.Mkdir(dir, 0755)
+	os.Chdir(dir)
+	os.Setenv("PWD", dir)
+	os.Remove(dir)
+
+	cwd := getValidCwd(true)
+	if cwd != dir {
+		t.Fatalf("getValidCwd() = %q, want %q", cwd, dir)
+	}
+
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.Modules = []string{"cwd", "git", "perms"}
+	cfg.CwdIgnoreErrors = true
+	cfg.CwdMode = "dironly"
+	p := newPowerline(cfg, cwd, alignLeft)
+	if len(p.Segments[0]) != 1 {
+		t.Fatalf("segments = %+v, want only the cwd segment", p.Segments[0])
+	}
+	if got, want := p.Segments[0][0].Content, "gone "+cwdDeletedMarker; got != want {
+		t.Errorf("cwd segment = %q, want %q", got, want)
+	}
+}
+
+func Test_cwdNativeToken(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{"bash", `\w`},
+		{"zsh", "%~"},
+		{"bare", "c"},
+	}
+	for _, tt := range tests {
+		cfg := defaults
+		cfg.CwdNativeToken = true
+		cfg.CwdMode = "dironly"
+		p := testPowerline(cfg)
+		p.shell = cfg.Shells[tt.shell]
+		p.cwd = "/a/b/c"
+
+		segments := segmentCwd(p)
+		if len(segments) != 1 || segments[0].Content != tt.want {
+			t.Errorf("segmentCwd() in %s = %+v, want %q", tt.shell, segments, tt.want)
+		}
+	}
+
+	// the width is the one of the path the shell shows, not of the token
+	cfg := defaults
+	cfg.Shell = "bash"
+	cfg.Mode = "ascii"
+	cfg.Modules = []string{}
+	cfg.CwdNativeToken = true
+	p := newPowerline(cfg, "/home/user/src/project", alignLeft)
+	p.userInfo = user.User{HomeDir: "/home/user"}
+	for _, segment := range segmentCwd(p) {
+		p.appendSegment("cwd", segment)
+	}
+	// ~/src/project, the padding and the separator
+	if got := p.Segments[0][0].Width; got != 13+2+1 {
+		t.Errorf("native token width = %d, want %d", got, 13+2+1)
+	}
+}
+
+func Test_cwdHeadTail(t *testing.T) {
+	tests := []struct {
+		cwd  string
+		head int
+		tail int
+		want []string
+	}{
+		{"/home/user/projects/go/src/main", 1, 2, []string{"~", ellipsis, "src", "main"}},
+		{"/home/user/projects/go/src/main", 2, 1, []string{"~", "projects", ellipsis, "main"}},
+		{"/home/user/projects/go", 1, 2, []string{"~", "projects", "go"}},
+		{"/srv/données/très/longue/arborescence/ici", 2, 2, []string{"srv", "données", ellipsis, "arborescence", "ici"}},
+		{"/a/b/c", 0, 0, []string{ellipsis, "c"}},
+	}
+	for _, tt := range tests {
+		cfg := defaults
+		cfg.Shell = "bare"
+		cfg.CwdMode = "head-tail"
+		cfg.CwdHeadCount = tt.head
+		cfg.CwdTailCount = tt.tail
+		p := testPowerline(cfg)
+		p.shell = cfg.Shells[cfg.Shell]
+		p.cwd = tt.cwd
+		p.userInfo = user.User{HomeDir: "/home/user"}
+
+		var got []string
+		for _, segment := range segmentCwd(p) {
+			got = append(got, segment.Content)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("segmentCwd(%q) with head %d and tail %d = %v, want %v", tt.cwd, tt.head, tt.tail, got, tt.want)
+		}
+	}
+
+	cfg := defaults
+	cfg.CwdMaxDirSize = 3
+	if got := maybeShortenName(testPowerline(cfg), "données"); got != "don" {
+		t.Errorf("maybeShortenName() = %q, want don", got)
+	}
+	cfg.CwdMaxDirSize = 2
+	if got := maybeShortenName(testPowerline(cfg), "étés"); got != "ét" {
+		t.Errorf("maybeShortenName() = %q, want ét", got)
+	}
+}
+
+func Test_cwdUniquePrefix(t *testing.T) {
+	home := t.TempDir()
+	for _, dir := range []string{"projects/powerline-go/src", "projects/prompt", "pictures", "music"} {
+		os.MkdirAll(filepath.Join(home, dir), 0755)
+	}
+
+	tests := []struct {
+		cwd  string
+		want []string
+	}{
+		{"projects/powerline-go/src", []string{"~", "pr", "po", "src"}},
+		{"projects/prompt", []string{"~", "pr", "prompt"}},
+		{"music", []string{"~", "music"}},
+		// parents that can't be read fall back to a single character
+		{"gone/alpha/beta", []string{"~", "g", "a", "beta"}},
+	}
+	for _, tt := range tests {
+		cfg := defaults
+		cfg.Shell = "bare"
+		cfg.CwdMode = "unique-prefix"
+		p := testPowerline(cfg)
+		p.shell = cfg.Shells[cfg.Shell]
+		p.cwd = filepath.Join(home, tt.cwd)
+		p.userInfo = user.User{HomeDir: home}
+
+		var got []string
+		for _, segment := range segmentCwd(p) {
+			got = append(got, segment.Content)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("segmentCwd(%q) = %v, want %v", tt.cwd, got, tt.want)
+		}
+	}
+}
+
+func Test_cwdMaxLength(t *testing.T) {
+	toSegments := func(paths ...string) []pathSegment {
+		var segments []pathSegment
+		for _, path := range paths {
+			segments = append(segments, pathSegment{path: path})
+		}
+		return segments
+	}
+	tests := []struct {
+		paths    []string
+		maxWidth int
+		want     []string
+	}{
+		{[]string{"srv", "写真", "旅行", "2024"}, 0, []string{"srv", "写真", "旅行", "2024"}},
+		{[]string{"srv", "写真", "旅行", "2024"}, 18, []string{"srv", "写真", "旅行", "2024"}},
+		// wide characters take up two columns each
+		{[]string{"srv", "写真", "旅行", "2024"}, 12, []string{ellipsis, "旅行", "2024"}},
+		{[]string{"notes", "😀😀😀😀"}, 6, []string{ellipsis + "😀😀"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, segment := range limitCwdWidth(toSegments(tt.paths...), tt.maxWidth) {
+			got = append(got, segment.path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("limitCwdWidth(%v, %d) = %v, want %v", tt.paths, tt.maxWidth, got, tt.want)
+		}
+	}
+
+	cfg := defaults
+	cfg.CwdMode = "plain"
+	cfg.CwdMaxLength = 8
+	p := testPowerline(cfg)
+	p.cwd = "/srv/写真/旅行"
+	p.userInfo = user.User{HomeDir: "/home/user"}
+	if got := segmentCwd(p)[0].Content; got != ellipsis+"真/旅行" {
+		t.Errorf("segmentCwd() = %q, want %q", got, ellipsis+"真/旅行")
+	}
+}
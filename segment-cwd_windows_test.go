@@ -0,0 +1,37 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os/user"
+	"reflect"
+	"testing"
+)
+
+func Test_cwdWindowsPaths(t *testing.T) {
+	p := testPowerline(defaults)
+	p.userInfo = user.User{HomeDir: `C:\Users\me`}
+
+	tests := []struct {
+		cwd  string
+		want []string
+	}{
+		{`C:\Users\me`, []string{"~"}},
+		{`C:\Users\me\src\project`, []string{"~", "src", "project"}},
+		{`c:\users\ME\src`, []string{"~", "src"}},
+		{`C:\Users\meow`, []string{"C:", "Users", "meow"}},
+		{`D:\work\x`, []string{"D:", "work", "x"}},
+		{`C:\`, []string{"C:"}},
+		{`\\server\share\dir`, []string{`\\server\share`, "dir"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, segment := range cwdToPathSegments(p, tt.cwd) {
+			got = append(got, segment.path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cwdToPathSegments(%q) = %v, want %v", tt.cwd, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+// dirTimeElapsed returns how long ago the Unix timestamp enteredAt was
+func dirTimeElapsed(enteredAt string, now time.Time) (time.Duration, bool) {
+	timestamp, err := strconv.ParseFloat(strings.Trim(enteredAt, "'\""), 64)
+	if err != nil {
+		return 0, false
+	}
+	elapsed := now.Sub(time.Unix(0, int64(timestamp*float64(time.Second))))
+	if elapsed < 0 {
+		return 0, false
+	}
+	return elapsed, true
+}
+
+func segmentDirTime(p *powerline) []pwl.Segment {
+	if p.cfg.DirEnteredAt == "" {
+		return []pwl.Segment{}
+	}
+	elapsed, ok := dirTimeElapsed(p.cfg.DirEnteredAt, time.Now())
+	if !ok {
+		return []pwl.Segment{}
+	}
+
+	return []pwl.Segment{{
+		Name:       "dirtime",
+		Content:    formatDuration(elapsed, false, false),
+		Foreground: p.theme.DurationFg,
+		Background: p.theme.DurationBg,
+	}}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_dirTime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		enteredAt string
+		want      string
+	}{
+		{"1699999958", "42s"},
+		{"'1699999400'", "10m 0s"},
+		{"1699992600.5", "2h 3m"},
+		{"1700000060", ""},
+		{"yesterday", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if elapsed, ok := dirTimeElapsed(tt.enteredAt, now); ok {
+			got = formatDuration(elapsed, false, false)
+		}
+		if got != tt.want {
+			t.Errorf("dirtime for %q = %q, want %q", tt.enteredAt, got, tt.want)
+		}
+	}
+
+	p := testPowerline(defaults)
+	if segments := segmentDirTime(p); len(segments) != 0 {
+		t.Errorf("segmentDirTime() without a timestamp = %+v, want none", segments)
+	}
+}
@@ -26,6 +26,44 @@ const (
 	hours        int64 = minutes * 60
 )
 
+// formatDuration renders duration with the two most significant units, down
+// to microseconds if the duration was given with precision
+func formatDuration(duration time.Duration, hasPrecision, lowPrecision bool) string {
+	var content string
+	ns := duration.Nanoseconds()
+	if ns > hours {
+		hrs := ns / hours
+		ns -= hrs * hours
+		mins := ns / minutes
+		content = fmt.Sprintf("%d%c %d%c", hrs, hour, mins, minute)
+	} else if ns > minutes {
+		mins := ns / minutes
+		ns -= mins * minutes
+		secs := ns / seconds
+		content = fmt.Sprintf("%d%c %d%c", mins, minute, secs, second)
+	} else if !hasPrecision {
+		secs := ns / seconds
+		content = fmt.Sprintf("%d%c", secs, second)
+	} else if ns > seconds {
+		secs := ns / seconds
+		ns -= secs * seconds
+		millis := ns / milliseconds
+		content = fmt.Sprintf("%d%c %d%c%c", secs, second, millis, milli, second)
+	} else if ns > milliseconds || lowPrecision {
+		millis := ns / milliseconds
+		ns -= millis * milliseconds
+		micros := ns / microseconds
+		if lowPrecision {
+			content = fmt.Sprintf("%d%c%c", millis, milli, second)
+		} else {
+			content = fmt.Sprintf("%d%c%c %d%c%c", millis, milli, second, micros, micro, second)
+		}
+	} else {
+		content = fmt.Sprintf("%d%c%c", ns/microseconds, micro, second)
+	}
+	return content
+}
+
 func segmentDuration(p *powerline) []pwl.Segment {
 	if p.cfg.Duration == "" {
 		return []pwl.Segment{{
@@ -62,38 +100,7 @@ func segmentDuration(p *powerline) []pwl.Segment {
 		return []pwl.Segment{}
 	}
 
-	var content string
-	ns := duration.Nanoseconds()
-	if ns > hours {
-		hrs := ns / hours
-		ns -= hrs * hours
-		mins := ns / minutes
-		content = fmt.Sprintf("%d%c %d%c", hrs, hour, mins, minute)
-	} else if ns > minutes {
-		mins := ns / minutes
-		ns -= mins * minutes
-		secs := ns / seconds
-		content = fmt.Sprintf("%d%c %d%c", mins, minute, secs, second)
-	} else if !hasPrecision {
-		secs := ns / seconds
-		content = fmt.Sprintf("%d%c", secs, second)
-	} else if ns > seconds {
-		secs := ns / seconds
-		ns -= secs * seconds
-		millis := ns / milliseconds
-		content = fmt.Sprintf("%d%c %d%c%c", secs, second, millis, milli, second)
-	} else if ns > milliseconds || p.cfg.DurationLowPrecision {
-		millis := ns / milliseconds
-		ns -= millis * milliseconds
-		micros := ns / microseconds
-		if p.cfg.DurationLowPrecision {
-			content = fmt.Sprintf("%d%c%c", millis, milli, second)
-		} else {
-			content = fmt.Sprintf("%d%c%c %d%c%c", millis, milli, second, micros, micro, second)
-		}
-	} else {
-		content = fmt.Sprintf("%d%c%c", ns/microseconds, micro, second)
-	}
+	content := formatDuration(duration, hasPrecision, p.cfg.DurationLowPrecision)
 
 	return []pwl.Segment{{
 		Name:       "duration",
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+func segmentEditor(p *powerline) []pwl.Segment {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	// editors may be configured with arguments, like "code --wait"
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return []pwl.Segment{}
+	}
+	return []pwl.Segment{{
+		Name:       "editor",
+		Content:    p.symbols.Editor + " " + escapeVariables(p, filepath.Base(fields[0])),
+		Foreground: p.theme.EditorFg,
+		Background: p.theme.EditorBg,
+	}}
+}
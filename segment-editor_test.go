@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_segmentEditor(t *testing.T) {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		value, found := os.LookupEnv(name)
+		name := name
+		t.Cleanup(func() {
+			if found {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+
+	cfg := defaults
+	cfg.Shell = "bare"
+	p := testPowerline(cfg)
+	p.shell = cfg.Shells[cfg.Shell]
+
+	tests := []struct {
+		visual string
+		editor string
+		want   string
+	}{
+		{"", "", ""},
+		{"", "/usr/bin/vim", "vim"},
+		{"code --wait", "vim", "code"},
+	}
+	for _, tt := range tests {
+		os.Setenv("VISUAL", tt.visual)
+		os.Setenv("EDITOR", tt.editor)
+		segments := segmentEditor(p)
+		if tt.want == "" {
+			if len(segments) != 0 {
+				t.Errorf("segmentEditor() = %+v, want no segments", segments)
+			}
+		} else if len(segments) != 1 || segments[0].Content != p.symbols.Editor+" "+tt.want {
+			t.Errorf("segmentEditor() = %+v, want %q", segments, tt.want)
+		}
+	}
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/justjanne/powerline-go/exitcode"
 	pwl "github.com/justjanne/powerline-go/powerline"
+	"github.com/shirou/gopsutil/v3/process"
 )
 
 var exitCodes = map[int]string{
@@ -49,21 +54,81 @@ func getMeaningFromExitCode(exitCode int) string {
 	return fmt.Sprintf("%d", exitCode)
 }
 
+// exitStatePath returns the file keeping the last failed exit code of the
+// shell that runs powerline-go
+func exitStatePath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	return filepath.Join(cacheDir, "powerline-go", fmt.Sprintf("exit-%d", os.Getppid()))
+}
+
+// updateStickyExit records a failed exit code of the command numbered command
+// in the state file at path and clears it once a command succeeds. It returns
+// the recorded exit code to show dimmed on prompts drawn without an exit code
+// or without a new command having run since, and 0 whenever the exit code is
+// that of a new command. Without a command number (0), every prompt with an
+// exit code counts as one after a new command.
+func updateStickyExit(path string, command int, errorSet bool, exitCode int) int {
+	state, err := ioutil.ReadFile(path)
+	recordedCommand, recorded := 0, 0
+	if err == nil {
+		fmt.Sscanf(string(state), "%d %d", &recordedCommand, &recorded)
+	}
+	switch {
+	case !errorSet || (command != 0 && command == recordedCommand):
+		return recorded
+	case exitCode == 0:
+		os.Remove(path)
+	default:
+		os.MkdirAll(filepath.Dir(path), 0700)
+		ioutil.WriteFile(path, []byte(fmt.Sprintf("%d %d", command, exitCode)), 0600)
+		removeStaleExitStates(filepath.Dir(path))
+	}
+	return 0
+}
+
+// removeStaleExitStates removes the state files of shells that have exited
+func removeStaleExitStates(dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		pid, err := strconv.Atoi(strings.TrimPrefix(file.Name(), "exit-"))
+		if err != nil || !strings.HasPrefix(file.Name(), "exit-") {
+			continue
+		}
+		if exists, err := process.PidExists(int32(pid)); err == nil && !exists {
+			os.Remove(filepath.Join(dir, file.Name()))
+		}
+	}
+}
+
 func segmentExitCode(p *powerline) []pwl.Segment {
 	var meaning string
-	if p.cfg.PrevError == 0 {
+	exitCode := p.cfg.PrevError
+	foreground, background := p.theme.CmdFailedFg, p.theme.CmdFailedBg
+	// a new failure is shown as it is, the sticky one only stands in for
+	// prompts without one
+	if p.cfg.StickyError != 0 && (exitCode == 0 || exitCode == p.cfg.StickyError) {
+		exitCode = p.cfg.StickyError
+		foreground, background = p.theme.CmdStickyFg, p.theme.CmdStickyBg
+	}
+	if exitCode == 0 {
 		return []pwl.Segment{}
 	}
 	if p.cfg.NumericExitCodes {
-		meaning = strconv.Itoa(p.cfg.PrevError)
+		meaning = strconv.Itoa(exitCode)
 	} else {
-		meaning = getMeaningFromExitCode(p.cfg.PrevError)
+		meaning = getMeaningFromExitCode(exitCode)
 	}
 
 	return []pwl.Segment{{
 		Name:       "exit",
 		Content:    meaning,
-		Foreground: p.theme.CmdFailedFg,
-		Background: p.theme.CmdFailedBg,
+		Foreground: foreground,
+		Background: background,
 	}}
 }
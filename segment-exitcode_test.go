@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_updateStickyExit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "powerline-go", "exit-1")
+	steps := []struct {
+		command  int
+		errorSet bool
+		exitCode int
+		want     int
+	}{
+		{0, false, 0, 0},
+		{1, true, 2, 0},
+		// shells pass the same exit code again until the next command runs
+		{1, true, 2, 2},
+		{0, false, 0, 2},
+		// a new command failing the same way is shown as a new failure
+		{2, true, 2, 0},
+		{2, true, 2, 2},
+		{3, true, 127, 0},
+		{3, true, 127, 127},
+		{0, false, 0, 127},
+		{4, true, 0, 0},
+		{4, true, 0, 0},
+		{0, false, 0, 0},
+		// without command numbers every exit code belongs to a new command
+		{0, true, 1, 0},
+		{0, true, 1, 0},
+		{0, false, 0, 1},
+	}
+	for i, step := range steps {
+		if got := updateStickyExit(path, step.command, step.errorSet, step.exitCode); got != step.want {
+			t.Errorf("step %d: updateStickyExit(%d, %v, %d) = %d, want %d", i, step.command, step.errorSet, step.exitCode, got, step.want)
+		}
+	}
+}
+
+func Test_removeStaleExitStates(t *testing.T) {
+	dir := t.TempDir()
+	alive := filepath.Join(dir, fmt.Sprintf("exit-%d", os.Getpid()))
+	dead := filepath.Join(dir, "exit-2147483000")
+	for _, path := range []string{alive, dead} {
+		ioutil.WriteFile(path, []byte("1"), 0600)
+	}
+
+	removeStaleExitStates(dir)
+	if !pathExists(alive) {
+		t.Errorf("removeStaleExitStates() removed the state of a running shell")
+	}
+	if pathExists(dead) {
+		t.Errorf("removeStaleExitStates() kept the state of an exited shell")
+	}
+}
+
+func Test_segmentExitCodeSticky(t *testing.T) {
+	cfg := defaults
+	cfg.StickyError = 2
+	p := testPowerline(cfg)
+
+	tests := []struct {
+		prevError  int
+		content    string
+		background uint8
+	}{
+		{0, "USAGE", p.theme.CmdStickyBg},
+		{2, "USAGE", p.theme.CmdStickyBg},
+		{1, "ERROR", p.theme.CmdFailedBg},
+	}
+	for _, tt := range tests {
+		p.cfg.PrevError = tt.prevError
+		segments := segmentExitCode(p)
+		if len(segments) != 1 || segments[0].Content != tt.content || segments[0].Background != tt.background {
+			t.Errorf("segmentExitCode() after %d = %+v, want %q on %d", tt.prevError, segments, tt.content, tt.background)
+		}
+	}
+}
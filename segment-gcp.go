@@ -59,34 +59,36 @@ func getGCPProjectFromGCloud() (string, error) {
 	return strings.TrimSuffix(string(out), "\n"), nil
 }
 
-func getGCPProjectFromFile() (string, error) {
+// readGCloudCoreProperties reads the properties in the [core] section of the
+// active gcloud configuration
+func readGCloudCoreProperties() (map[string]string, error) {
 	configDir, err := getCloudConfigDir()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	activeConfig, err := getActiveGCloudConfig(configDir)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	configPath := configDir + "/configurations/config_" + activeConfig
 	stat, err := os.Stat(configPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	} else if stat.IsDir() {
-		return "", fmt.Errorf("%s is a directory", configPath)
+		return nil, fmt.Errorf("%s is a directory", configPath)
 	}
 
 	b, err := ioutil.ReadFile(configPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	b = append([]byte("\n"), b...)
 
 	coreStart := bytes.Index(b, []byte(gcloudCoreSectionHeader))
 	if coreStart == -1 {
-		return "", fmt.Errorf("could not find [core] section in %s", configPath)
+		return nil, fmt.Errorf("could not find [core] section in %s", configPath)
 	}
 	b = b[coreStart+len(gcloudCoreSectionHeader):]
 
@@ -95,39 +97,38 @@ func getGCPProjectFromFile() (string, error) {
 		b = b[:coreEnd]
 	}
 
-	lines := bytes.Split(b[coreStart+len(gcloudCoreSectionHeader):coreEnd], []byte("\n"))
-	for _, line := range lines {
-		parts := bytes.Split(line, []byte("="))
+	properties := make(map[string]string)
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		parts := bytes.SplitN(line, []byte("="), 2)
 		if len(parts) == 2 {
-			if strings.TrimSpace(string(parts[0])) == "project" {
-				return strings.TrimSpace(string(parts[1])), nil
-			}
+			properties[strings.TrimSpace(string(parts[0]))] = strings.TrimSpace(string(parts[1]))
 		}
 	}
-
-	return "", nil
-}
-
-func getGCPProject() (string, error) {
-	if project, err := getGCPProjectFromFile(); err == nil {
-		return project, nil
-	} else {
-		return getGCPProjectFromGCloud()
-	}
+	return properties, nil
 }
 
 func segmentGCP(p *powerline) []pwl.Segment {
-	project, err := getGCPProject()
+	properties, err := readGCloudCoreProperties()
+	if err != nil && p.cfg.GCPConfigFilesOnly {
+		return []pwl.Segment{}
+	}
+	project := properties["project"]
 	if err != nil {
-		log.Fatal(err)
+		project, err = getGCPProjectFromGCloud()
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	if project == "" {
 		return []pwl.Segment{}
 	}
+	if account := properties["account"]; p.cfg.GCPShowAccount && account != "" {
+		project += " (" + account + ")"
+	}
 	return []pwl.Segment{{
 		Name:       "gcp",
-		Content:    project,
+		Content:    escapeVariables(p, project),
 		Foreground: p.theme.GCPFg,
 		Background: p.theme.GCPBg,
 	}}
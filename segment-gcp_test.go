@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_segmentGCP(t *testing.T) {
+	home, found := os.LookupEnv("HOME")
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("HOME", home)
+		} else {
+			os.Unsetenv("HOME")
+		}
+	})
+	os.Setenv("HOME", t.TempDir())
+	configDir, _ := getCloudConfigDir()
+
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.GCPConfigFilesOnly = true
+	p := testPowerline(cfg)
+	p.shell = cfg.Shells[cfg.Shell]
+	if segments := segmentGCP(p); len(segments) != 0 {
+		t.Errorf("segmentGCP() without gcloud config = %+v, want none", segments)
+	}
+
+	os.MkdirAll(filepath.Join(configDir, "configurations"), 0755)
+	ioutil.WriteFile(filepath.Join(configDir, "active_config"), []byte("work\n"), 0644)
+	ioutil.WriteFile(filepath.Join(configDir, "configurations", "config_work"), []byte(
+		"[compute]\nzone = europe-west1-b\n\n[core]\naccount = me@example.com\nproject = my-project\n"), 0644)
+
+	tests := []struct {
+		showAccount bool
+		want        string
+	}{
+		{false, "my-project"},
+		{true, "my-project (me@example.com)"},
+	}
+	for _, tt := range tests {
+		p.cfg.GCPShowAccount = tt.showAccount
+		segments := segmentGCP(p)
+		if len(segments) != 1 || segments[0].Content != tt.want {
+			t.Errorf("segmentGCP() with account %v = %+v, want %q", tt.showAccount, segments, tt.want)
+		}
+	}
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	gitconfig "github.com/go-git/go-git/v5/plumbing/format/config"
 	pwl "github.com/justjanne/powerline-go/powerline"
+	"github.com/mattn/go-runewidth"
 )
 
 type repoStats struct {
@@ -20,21 +27,56 @@ type repoStats struct {
 	staged     int
 	conflicted int
 	stashed    int
+	stashFiles int
+	// stashedWIP and stashedNamed split stashed into automatic WIP stashes
+	// and stashes with a message, both are zero if stashes aren't classified
+	stashedWIP   int
+	stashedNamed int
+	ignored      int
+	submodules   int
+	pushAhead    int
+	pushBehind   int
+	// behindDefault counts the commits on the default branch of origin
+	// that aren't on the current branch
+	behindDefault int
+	upstream      bool
 }
 
 func (r repoStats) dirty() bool {
 	return r.untracked+r.notStaged+r.staged+r.conflicted > 0
 }
 
+// dirtyBranch reports whether the branch should be colored as dirty, which
+// can leave untracked files out
+func (r repoStats) dirtyBranch(p *powerline) bool {
+	if p.cfg.GitUntrackedNotDirty {
+		return r.notStaged+r.staged+r.conflicted > 0
+	}
+	return r.dirty()
+}
+
 func (r repoStats) any() bool {
-	return r.ahead+r.behind+r.untracked+r.notStaged+r.staged+r.conflicted+r.stashed > 0
+	return r.ahead+r.behind+r.pushAhead+r.pushBehind+r.behindDefault+r.untracked+r.notStaged+r.staged+r.conflicted+r.stashed > 0
+}
+
+// formatRepoStatsCount renders nChanges, or limit+ if it exceeds a non-zero
+// limit
+func formatRepoStatsCount(nChanges int, limit int) string {
+	if limit > 0 && nChanges > limit {
+		return fmt.Sprintf("%d+", limit)
+	}
+	return strconv.Itoa(nChanges)
 }
 
 func addRepoStatsSegment(nChanges int, symbol string, foreground uint8, background uint8) []pwl.Segment {
+	return addCappedRepoStatsSegment(nChanges, 0, symbol, foreground, background)
+}
+
+func addCappedRepoStatsSegment(nChanges int, limit int, symbol string, foreground uint8, background uint8) []pwl.Segment {
 	if nChanges > 0 {
 		return []pwl.Segment{{
 			Name:       "git-status",
-			Content:    fmt.Sprintf("%d%s", nChanges, symbol),
+			Content:    formatRepoStatsCount(nChanges, limit) + symbol,
 			Foreground: foreground,
 			Background: background,
 		}}
@@ -42,23 +84,178 @@ func addRepoStatsSegment(nChanges int, symbol string, foreground uint8, backgrou
 	return []pwl.Segment{}
 }
 
+// syncState returns the glyph and colors describing how the branch relates
+// to its upstream, or an empty glyph if there is nothing to report.
+func (r repoStats) syncState(p *powerline) (string, uint8, uint8) {
+	switch {
+	case r.ahead > 0 && r.behind > 0:
+		return p.symbols.RepoSyncDiverged, p.theme.GitSyncDivergedFg, p.theme.GitSyncDivergedBg
+	case r.ahead > 0:
+		return p.symbols.RepoSyncAhead, p.theme.GitSyncAheadFg, p.theme.GitSyncAheadBg
+	case r.behind > 0:
+		return p.symbols.RepoSyncBehind, p.theme.GitSyncBehindFg, p.theme.GitSyncBehindBg
+	case r.upstream:
+		return p.symbols.RepoSynced, p.theme.GitSyncedFg, p.theme.GitSyncedBg
+	}
+	return "", 0, 0
+}
+
+// divergedToken renders the ahead and behind counts as one diverged token
+func (r repoStats) divergedToken(p *powerline) string {
+	return fmt.Sprintf("%s%s/%s", p.symbols.RepoSyncDiverged,
+		formatRepoStatsCount(r.ahead, p.cfg.GitAheadBehindCap),
+		formatRepoStatsCount(r.behind, p.cfg.GitAheadBehindCap))
+}
+
+// dominantChange names the kind of change -git-mode=micro colors its total by.
+// Any conflict wins, otherwise the most common kind does, with ties going to
+// the kind listed first in the status.
+func (r repoStats) dominantChange() string {
+	if r.conflicted > 0 {
+		return "conflicted"
+	}
+	dominant, most := "", 0
+	for _, change := range []struct {
+		kind  string
+		count int
+	}{
+		{"staged", r.staged},
+		{"not-staged", r.notStaged},
+		{"untracked", r.untracked},
+	} {
+		if change.count > most {
+			dominant, most = change.kind, change.count
+		}
+	}
+	return dominant
+}
+
+// GitMicroSegments renders the total number of changed files as one segment
+// on the background of a dirty branch, its text colored like the dominant
+// kind of change
+func (r repoStats) GitMicroSegments(p *powerline) []pwl.Segment {
+	total := r.staged + r.notStaged + r.untracked + r.conflicted
+	if total == 0 {
+		return []pwl.Segment{}
+	}
+	var foreground uint8
+	switch r.dominantChange() {
+	case "conflicted":
+		foreground = p.theme.GitConflictedFg
+	case "staged":
+		foreground = p.theme.GitStagedFg
+	case "not-staged":
+		foreground = p.theme.GitNotStagedFg
+	default:
+		foreground = p.theme.GitUntrackedFg
+	}
+	return []pwl.Segment{{
+		Name:       "git-status",
+		Content:    fmt.Sprintf("%s%d", p.symbols.RepoChanges, total),
+		Foreground: foreground,
+		Background: p.theme.RepoDirtyBg,
+	}}
+}
+
+// dotSeverity names the most severe state of the repository, which
+// -git-mode=dot colors its dot by: conflicts, then changes, then commits to
+// push or pull, and finally clean
+func (r repoStats) dotSeverity(p *powerline) string {
+	switch {
+	case r.conflicted > 0:
+		return "conflicted"
+	case r.dirtyBranch(p):
+		return "dirty"
+	case r.ahead+r.behind > 0:
+		return "diverged"
+	}
+	return "clean"
+}
+
+// GitDotSegments renders the state of the repository as a single dot
+func (r repoStats) GitDotSegments(p *powerline) []pwl.Segment {
+	var foreground, background uint8
+	switch r.dotSeverity(p) {
+	case "conflicted":
+		foreground, background = p.theme.GitConflictedFg, p.theme.GitConflictedBg
+	case "dirty":
+		foreground, background = p.theme.GitNotStagedFg, p.theme.GitNotStagedBg
+	case "diverged":
+		foreground, background = p.theme.GitSyncAheadFg, p.theme.GitSyncAheadBg
+	default:
+		foreground, background = p.theme.GitSyncedFg, p.theme.GitSyncedBg
+	}
+	return []pwl.Segment{{
+		Name:       "git-status",
+		Content:    p.symbols.RepoDot,
+		Foreground: foreground,
+		Background: background,
+	}}
+}
+
 func (r repoStats) GitSegments(p *powerline) (segments []pwl.Segment) {
-	segments = append(segments, addRepoStatsSegment(r.ahead, p.symbols.RepoAhead, p.theme.GitAheadFg, p.theme.GitAheadBg)...)
-	segments = append(segments, addRepoStatsSegment(r.behind, p.symbols.RepoBehind, p.theme.GitBehindFg, p.theme.GitBehindBg)...)
+	if p.cfg.GitConflictProminent {
+		segments = append(segments, addRepoStatsSegment(r.conflicted, p.symbols.RepoConflicted, p.theme.GitConflictProminentFg, p.theme.GitConflictProminentBg)...)
+		r.conflicted = 0
+	}
+	if p.cfg.GitSyncGlyphMode {
+		if symbol, foreground, background := r.syncState(p); symbol != "" {
+			segments = append(segments, pwl.Segment{
+				Name:       "git-status",
+				Content:    symbol,
+				Foreground: foreground,
+				Background: background,
+			})
+		}
+	} else if p.cfg.GitDivergedGlyph && r.ahead > 0 && r.behind > 0 {
+		segments = append(segments, pwl.Segment{
+			Name:       "git-status",
+			Content:    r.divergedToken(p),
+			Foreground: p.theme.GitSyncDivergedFg,
+			Background: p.theme.GitSyncDivergedBg,
+		})
+	} else {
+		segments = append(segments, addCappedRepoStatsSegment(r.ahead, p.cfg.GitAheadBehindCap, p.symbols.RepoAhead, p.theme.GitAheadFg, p.theme.GitAheadBg)...)
+		segments = append(segments, addCappedRepoStatsSegment(r.behind, p.cfg.GitAheadBehindCap, p.symbols.RepoBehind, p.theme.GitBehindFg, p.theme.GitBehindBg)...)
+	}
+	segments = append(segments, addCappedRepoStatsSegment(r.pushAhead, p.cfg.GitAheadBehindCap, p.symbols.RepoPushAhead, p.theme.GitPushAheadFg, p.theme.GitPushAheadBg)...)
+	segments = append(segments, addCappedRepoStatsSegment(r.pushBehind, p.cfg.GitAheadBehindCap, p.symbols.RepoPushBehind, p.theme.GitPushBehindFg, p.theme.GitPushBehindBg)...)
+	segments = append(segments, addCappedRepoStatsSegment(r.behindDefault, p.cfg.GitAheadBehindCap, p.symbols.RepoBehindDefault, p.theme.GitBehindDefaultFg, p.theme.GitBehindDefaultBg)...)
 	segments = append(segments, addRepoStatsSegment(r.staged, p.symbols.RepoStaged, p.theme.GitStagedFg, p.theme.GitStagedBg)...)
 	segments = append(segments, addRepoStatsSegment(r.notStaged, p.symbols.RepoNotStaged, p.theme.GitNotStagedFg, p.theme.GitNotStagedBg)...)
 	segments = append(segments, addRepoStatsSegment(r.untracked, p.symbols.RepoUntracked, p.theme.GitUntrackedFg, p.theme.GitUntrackedBg)...)
 	segments = append(segments, addRepoStatsSegment(r.conflicted, p.symbols.RepoConflicted, p.theme.GitConflictedFg, p.theme.GitConflictedBg)...)
-	segments = append(segments, addRepoStatsSegment(r.stashed, p.symbols.RepoStashed, p.theme.GitStashedFg, p.theme.GitStashedBg)...)
+	if r.stashedWIP+r.stashedNamed > 0 {
+		segments = append(segments, addRepoStatsSegment(r.stashedWIP, p.symbols.RepoStashed, p.theme.GitStashedFg, p.theme.GitStashedBg)...)
+		segments = append(segments, addRepoStatsSegment(r.stashedNamed, p.symbols.RepoStashNamed, p.theme.GitStashedFg, p.theme.GitStashedBg)...)
+	} else {
+		segments = append(segments, addRepoStatsSegment(r.stashed, p.symbols.RepoStashed, p.theme.GitStashedFg, p.theme.GitStashedBg)...)
+	}
+	if r.stashFiles > 0 {
+		segments = append(segments, pwl.Segment{
+			Name:       "git-status",
+			Content:    fmt.Sprintf("%s%df", p.symbols.RepoStashed, r.stashFiles),
+			Foreground: p.theme.GitStashedFg,
+			Background: p.theme.GitStashedBg,
+		})
+	}
+	segments = append(segments, addRepoStatsSegment(r.ignored, p.symbols.RepoIgnored, p.theme.GitIgnoredFg, p.theme.GitIgnoredBg)...)
+	segments = append(segments, addRepoStatsSegment(r.submodules, p.symbols.RepoSubmodules, p.theme.GitSubmodulesFg, p.theme.GitSubmodulesBg)...)
 	return
 }
 
 func addRepoStatsSymbol(nChanges int, symbol string, GitMode string) string {
+	return addCappedRepoStatsSymbol(nChanges, 0, symbol, GitMode)
+}
+
+func addCappedRepoStatsSymbol(nChanges int, limit int, symbol string, GitMode string) string {
 	if nChanges > 0 {
 		if GitMode == "simple" {
 			return symbol
 		} else if GitMode == "compact" {
-			return fmt.Sprintf(" %d%s", nChanges, symbol )
+			return fmt.Sprintf(" %s%s", formatRepoStatsCount(nChanges, limit), symbol)
+		} else if GitMode == "counts" {
+			return formatRepoStatsCount(nChanges, limit) + symbol
 		} else {
 			return symbol
 		}
@@ -68,17 +265,59 @@ func addRepoStatsSymbol(nChanges int, symbol string, GitMode string) string {
 
 func (r repoStats) GitSymbols(p *powerline) string {
 	var info string
-	info += addRepoStatsSymbol(r.ahead, p.symbols.RepoAhead, p.cfg.GitMode)
-	info += addRepoStatsSymbol(r.behind, p.symbols.RepoBehind, p.cfg.GitMode)
+	if p.cfg.GitConflictProminent {
+		info += addRepoStatsSymbol(r.conflicted, p.symbols.RepoConflicted, p.cfg.GitMode)
+		r.conflicted = 0
+	}
+	if p.cfg.GitSyncGlyphMode {
+		if symbol, _, _ := r.syncState(p); symbol != "" {
+			if p.cfg.GitMode == "compact" {
+				info += " "
+			}
+			info += symbol
+		}
+	} else if p.cfg.GitDivergedGlyph && r.ahead > 0 && r.behind > 0 {
+		switch p.cfg.GitMode {
+		case "compact":
+			info += " " + r.divergedToken(p)
+		case "counts":
+			info += r.divergedToken(p)
+		default:
+			info += p.symbols.RepoSyncDiverged
+		}
+	} else {
+		info += addCappedRepoStatsSymbol(r.ahead, p.cfg.GitAheadBehindCap, p.symbols.RepoAhead, p.cfg.GitMode)
+		info += addCappedRepoStatsSymbol(r.behind, p.cfg.GitAheadBehindCap, p.symbols.RepoBehind, p.cfg.GitMode)
+	}
+	info += addCappedRepoStatsSymbol(r.pushAhead, p.cfg.GitAheadBehindCap, p.symbols.RepoPushAhead, p.cfg.GitMode)
+	info += addCappedRepoStatsSymbol(r.pushBehind, p.cfg.GitAheadBehindCap, p.symbols.RepoPushBehind, p.cfg.GitMode)
+	info += addCappedRepoStatsSymbol(r.behindDefault, p.cfg.GitAheadBehindCap, p.symbols.RepoBehindDefault, p.cfg.GitMode)
 	info += addRepoStatsSymbol(r.staged, p.symbols.RepoStaged, p.cfg.GitMode)
 	info += addRepoStatsSymbol(r.notStaged, p.symbols.RepoNotStaged, p.cfg.GitMode)
 	info += addRepoStatsSymbol(r.untracked, p.symbols.RepoUntracked, p.cfg.GitMode)
 	info += addRepoStatsSymbol(r.conflicted, p.symbols.RepoConflicted, p.cfg.GitMode)
-	info += addRepoStatsSymbol(r.stashed, p.symbols.RepoStashed, p.cfg.GitMode)
+	if r.stashedWIP+r.stashedNamed > 0 {
+		info += addRepoStatsSymbol(r.stashedWIP, p.symbols.RepoStashed, p.cfg.GitMode)
+		info += addRepoStatsSymbol(r.stashedNamed, p.symbols.RepoStashNamed, p.cfg.GitMode)
+	} else {
+		info += addRepoStatsSymbol(r.stashed, p.symbols.RepoStashed, p.cfg.GitMode)
+	}
+	if r.stashFiles > 0 {
+		if p.cfg.GitMode == "compact" {
+			info += " "
+		}
+		info += fmt.Sprintf("%s%df", p.symbols.RepoStashed, r.stashFiles)
+	}
+	info += addRepoStatsSymbol(r.ignored, p.symbols.RepoIgnored, p.cfg.GitMode)
+	info += addRepoStatsSymbol(r.submodules, p.symbols.RepoSubmodules, p.cfg.GitMode)
 	return info
 }
 
-var branchRegex = regexp.MustCompile(`^## (?P<local>\S+?)(\.{3}(?P<remote>\S+?)( \[(ahead (?P<ahead>\d+)(, )?)?(behind (?P<behind>\d+))?])?)?$`)
+// unbornBranchRegex matches the status of a repository without any commits,
+// older versions of git call it the initial commit
+var unbornBranchRegex = regexp.MustCompile(`^## (?:No commits yet|Initial commit) on (\S+)$`)
+
+var branchRegex = regexp.MustCompile(`^## (?P<local>\S+?)(\.{3}(?P<remote>\S+?)( \[((?P<gone>gone)|(?P<different>different)|(ahead (?P<ahead>\d+)(, )?)?(behind (?P<behind>\d+))?)])?)?$`)
 
 func groupDict(pattern *regexp.Regexp, haystack string) map[string]string {
 	match := pattern.FindStringSubmatch(haystack)
@@ -109,8 +348,18 @@ var gitProcessEnv = func() []string {
 	return result
 }()
 
+// gitStatus runs git status, it is a variable so tests can inject failures
+var gitStatus = func(args ...string) (string, error) {
+	return runGitCommand("git", append([]string{"status"}, args...)...)
+}
+
 func runGitCommand(cmd string, args ...string) (string, error) {
-	command := exec.Command(cmd, args...)
+	return runGitCommandContext(context.Background(), cmd, args...)
+}
+
+// runGitCommandContext is runGitCommand, killing the command once ctx is done
+func runGitCommandContext(ctx context.Context, cmd string, args ...string) (string, error) {
+	command := exec.CommandContext(ctx, cmd, args...)
 	command.Env = gitProcessEnv
 	out, err := command.Output()
 	return string(out), err
@@ -120,6 +369,86 @@ func parseGitBranchInfo(status []string) map[string]string {
 	return groupDict(branchRegex, status[0])
 }
 
+// gitUpstreamState describes whether a branch has an upstream to compare to
+type gitUpstreamState int
+
+const (
+	// gitUpstreamNone means no upstream is configured
+	gitUpstreamNone gitUpstreamState = iota
+	// gitUpstreamGone means the configured upstream branch no longer exists
+	gitUpstreamGone
+	gitUpstreamTracking
+	// gitUpstreamFallback means no upstream is configured, but a remote has a
+	// branch of the same name to compare to instead
+	gitUpstreamFallback
+)
+
+// parseGitUpstreamState tells apart the branches ahead and behind counts are
+// zero for because they have nothing to compare to
+func parseGitUpstreamState(branchInfo map[string]string) gitUpstreamState {
+	switch {
+	case branchInfo["remote"] == "":
+		return gitUpstreamNone
+	case branchInfo["gone"] != "":
+		return gitUpstreamGone
+	default:
+		return gitUpstreamTracking
+	}
+}
+
+// resolveGitUpstream returns the ref branch is compared to and its state. With
+// -git-upstream-fallback, a branch without an upstream is compared to the
+// branch of the same name on a remote.
+func resolveGitUpstream(p *powerline, branch string, branchInfo map[string]string) (string, gitUpstreamState) {
+	state := parseGitUpstreamState(branchInfo)
+	if state == gitUpstreamNone && p.cfg.GitUpstreamFallback {
+		if fallback := getGitFallbackUpstream(branch); fallback != "" {
+			return fallback, gitUpstreamFallback
+		}
+	}
+	return branchInfo["remote"], state
+}
+
+// getGitFallbackUpstream returns the remote-tracking ref of the same name as
+// branch, looking at origin before the other remotes, or an empty string if no
+// remote has one
+func getGitFallbackUpstream(branch string) string {
+	out, err := runGitCommand("git", "remote")
+	if err != nil {
+		return ""
+	}
+	remotes := strings.Fields(out)
+	for idx, remote := range remotes {
+		if remote == "origin" {
+			remotes = append([]string{remote}, append(remotes[:idx:idx], remotes[idx+1:]...)...)
+			break
+		}
+	}
+	for _, remote := range remotes {
+		ref := remote + "/" + branch
+		if _, err := runGitCommand("git", "rev-parse", "--verify", "--quiet", "refs/remotes/"+ref); err == nil {
+			return ref
+		}
+	}
+	return ""
+}
+
+// shortSymbolicRef shortens the ref HEAD points to, and reports whether it is
+// something other than a local branch, like refs/remotes/origin/main
+func shortSymbolicRef(ref string) (string, bool) {
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		return strings.TrimPrefix(ref, "refs/heads/"), false
+	case strings.HasPrefix(ref, "refs/remotes/"):
+		return strings.TrimPrefix(ref, "refs/remotes/"), true
+	case strings.HasPrefix(ref, "refs/tags/"):
+		return strings.TrimPrefix(ref, "refs/tags/"), true
+	case strings.HasPrefix(ref, "refs/"):
+		return strings.TrimPrefix(ref, "refs/"), true
+	}
+	return ref, false
+}
+
 func getGitDetachedBranch(p *powerline) string {
 	out, err := runGitCommand("git", "rev-parse", "--short", "HEAD")
 	if err != nil {
@@ -130,7 +459,361 @@ func getGitDetachedBranch(p *powerline) string {
 		return strings.SplitN(out, "\n", 2)[0]
 	}
 	detachedRef := strings.SplitN(out, "\n", 2)
-	return fmt.Sprintf("%s %s", p.symbols.RepoDetached, detachedRef[0])
+	detached := expandPlaceholders(p.cfg.GitDetachedFormat, map[string]func() string{
+		"short": func() string {
+			return detachedRef[0]
+		},
+		"full": func() string {
+			out, _ := runGitCommand("git", "rev-parse", "HEAD")
+			return strings.TrimSpace(out)
+		},
+		"tag": func() string {
+			out, _ := runGitCommand("git", "describe", "--tags", "--exact-match", "HEAD")
+			return strings.TrimSpace(out)
+		},
+	})
+	return fmt.Sprintf("%s %s", p.symbols.RepoDetached, detached)
+}
+
+// gitAheadBehind counts the commits only reachable from local and the commits
+// only reachable from upstream
+func gitAheadBehind(local, upstream string) (int, int, error) {
+	out, err := runGitCommand("git", "rev-list", "--left-right", "--count", local+"..."+upstream)
+	if err != nil {
+		return 0, 0, err
+	}
+	counts := strings.Fields(out)
+	if len(counts) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", out)
+	}
+	ahead, _ := strconv.Atoi(counts[0])
+	behind, _ := strconv.Atoi(counts[1])
+	return ahead, behind, nil
+}
+
+// gitRevCount counts the commits in revisions, capped at max if max is
+// positive
+func gitRevCount(revisions string, max int) (int, error) {
+	out, err := runGitCommand("git", "rev-list", "--count", "--max-count="+strconv.Itoa(max), revisions)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// gitAheadBehindLimited is gitAheadBehind, but caps either count at more than
+// max commits, so counts above max are only known to exceed it. A max of 0
+// counts all commits.
+func gitAheadBehindLimited(local, upstream string, max int) (int, int, error) {
+	if max <= 0 {
+		return gitAheadBehind(local, upstream)
+	}
+	ahead, err := gitRevCount(upstream+".."+local, max+1)
+	if err != nil {
+		return 0, 0, err
+	}
+	behind, err := gitRevCount(local+".."+upstream, max+1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return ahead, behind, nil
+}
+
+// gitHeadPushed reports whether HEAD is reachable from any remote-tracking
+// branch. The second result is false if the repository has no remote-tracking
+// branches, so there is nowhere HEAD could have been pushed to.
+func gitHeadPushed() (bool, bool) {
+	out, err := runGitCommand("git", "for-each-ref", "--count=1", "--format=%(refname)", "refs/remotes")
+	if err != nil || strings.TrimSpace(out) == "" {
+		return false, false
+	}
+	out, err = runGitCommand("git", "for-each-ref", "--count=1", "--contains", "HEAD", "--format=%(refname)", "refs/remotes")
+	if err != nil {
+		return false, false
+	}
+	return strings.TrimSpace(out) != "", true
+}
+
+// getGitDetachedUpstream returns the upstream of the first local branch
+// containing the detached HEAD
+func getGitDetachedUpstream() string {
+	out, err := runGitCommand("git", "for-each-ref", "--contains", "HEAD", "--format=%(upstream:short)", "refs/heads")
+	if err != nil {
+		return ""
+	}
+	for _, upstream := range strings.Split(out, "\n") {
+		if upstream = strings.TrimSpace(upstream); upstream != "" {
+			return upstream
+		}
+	}
+	return ""
+}
+
+// getGitDefaultBranch returns the remote-tracking ref origin/HEAD points to,
+// or an empty string if it isn't set
+func getGitDefaultBranch() string {
+	out, err := runGitCommand("git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// gitConfigValue returns the value of a git config key, or an empty string
+// if it isn't set
+func gitConfigValue(key string) string {
+	out, err := runGitCommand("git", "config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// getGitPushDestination returns the remote branch git push pushes branch to,
+// using the push configuration and falling back to the fetch configuration
+func getGitPushDestination(branch string) string {
+	fetchRemote := gitConfigValue("branch." + branch + ".remote")
+	remote := gitConfigValue("branch." + branch + ".pushRemote")
+	if remote == "" {
+		remote = gitConfigValue("remote.pushDefault")
+	}
+	if remote == "" {
+		remote = fetchRemote
+	}
+	if remote == "" {
+		return ""
+	}
+
+	// only pushing to the upstream uses a different branch name, and only
+	// when the push goes to the remote the branch is fetched from
+	target := branch
+	switch gitConfigValue("push.default") {
+	case "upstream", "tracking":
+		if remote == fetchRemote {
+			if merge := gitConfigValue("branch." + branch + ".merge"); merge != "" {
+				target = strings.TrimPrefix(merge, "refs/heads/")
+			}
+		}
+	}
+	return remote + "/" + target
+}
+
+// getGitRemoteName returns the name of the remote branch tracks
+func getGitRemoteName(branch string) string {
+	out, err := runGitCommand("git", "config", "--get", "branch."+branch+".remote")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// gitIgnoredModifiedCount returns the number of tracked files that match
+// ignore rules and are modified in the work tree
+func gitIgnoredModifiedCount() int {
+	out, err := runGitCommand("git", "ls-files", "-t", "-i", "-c", "--exclude-standard", "-m")
+	if err != nil {
+		return 0
+	}
+	// -c and -m list a modified file twice, only the -m entry is tagged C
+	count := 0
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "C ") {
+			count++
+		}
+	}
+	return count
+}
+
+// gitRemoteCount returns the number of remotes configured for the repository
+func gitRemoteCount() int {
+	out, err := runGitCommand("git", "remote")
+	if err != nil {
+		return 0
+	}
+	return len(strings.Fields(out))
+}
+
+// getGitPushTarget returns the remote-tracking ref that branch is pushed to,
+// as configured by branch.<name>.pushRemote or remote.pushDefault, or an
+// empty string if no separate push remote is configured
+func getGitPushTarget(branch string) string {
+	remote, err := runGitCommand("git", "config", "--get", "branch."+branch+".pushRemote")
+	if err != nil {
+		remote, err = runGitCommand("git", "config", "--get", "remote.pushDefault")
+		if err != nil {
+			return ""
+		}
+	}
+	target := strings.TrimSpace(remote) + "/" + branch
+	if _, err := runGitCommand("git", "rev-parse", "--verify", "--quiet", "refs/remotes/"+target); err != nil {
+		return ""
+	}
+	return target
+}
+
+// gitStashFileCount counts the files changed by the most recent stash
+func gitStashFileCount() (int, error) {
+	out, err := runGitCommand("git", "diff", "--name-only", "refs/stash^1", "refs/stash")
+	if err != nil {
+		return 0, err
+	}
+	return strings.Count(out, "\n"), nil
+}
+
+// classifyGitStashes splits the stash reflog subjects into stashes git named
+// "WIP on <branch>" itself and stashes saved with a message, which are named
+// "On <branch>". It returns false if any subject matches neither.
+func classifyGitStashes(subjects string) (int, int, bool) {
+	wip, named := 0, 0
+	for _, subject := range strings.Split(strings.TrimSpace(subjects), "\n") {
+		switch {
+		case subject == "":
+			continue
+		case strings.HasPrefix(subject, "WIP on "):
+			wip++
+		case strings.HasPrefix(subject, "On "):
+			named++
+		default:
+			return 0, 0, false
+		}
+	}
+	return wip, named, true
+}
+
+// getGitCommitTime returns when HEAD was committed, or when it was authored
+// if dateKind is "author", which differs after rebases and cherry-picks
+func getGitCommitTime(dateKind string) (time.Time, error) {
+	format := "--format=%ct"
+	if dateKind == "author" {
+		format = "--format=%at"
+	}
+	out, err := runGitCommand("git", "log", "-1", format)
+	if err != nil {
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// getGitMergeBase returns the short hash of the best common ancestor of HEAD
+// and base, which defaults to the upstream of the current branch
+func getGitMergeBase(base string) (string, error) {
+	if base == "" {
+		base = "@{upstream}"
+	}
+	out, err := runGitCommand("git", "merge-base", "HEAD", base)
+	if err != nil {
+		return "", err
+	}
+	hash := strings.TrimSpace(out)
+	if len(hash) > 7 {
+		hash = hash[:7]
+	}
+	return hash, nil
+}
+
+// getGitCommitSubject returns the first line of the message of HEAD, shortened
+// to maxLength columns, or an empty string in a repository without commits
+func getGitCommitSubject(maxLength int) string {
+	out, err := runGitCommand("git", "log", "-1", "--format=%s")
+	if err != nil {
+		return ""
+	}
+	subject := strings.TrimSpace(out)
+	if maxLength > 0 && runewidth.StringWidth(subject) > maxLength {
+		subject = runewidth.Truncate(subject, maxLength, ellipsis)
+	}
+	return subject
+}
+
+// enclosingGitRepo returns the root and branch of the repository root is
+// nested in, if there is one
+func enclosingGitRepo(root string) (string, string, bool) {
+	parent := filepath.Dir(root)
+	if parent == root {
+		return "", "", false
+	}
+	out, err := runGitCommand("git", "-C", parent, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", "", false
+	}
+	outer := strings.TrimSpace(out)
+	branch, err := runGitCommand("git", "-C", outer, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		// detached, show the commit instead
+		branch, err = runGitCommand("git", "-C", outer, "rev-parse", "--short", "HEAD")
+		if err != nil {
+			return "", "", false
+		}
+	}
+	return outer, strings.TrimSpace(branch), true
+}
+
+// formatGitAge renders age in its largest whole unit, from minutes to years
+func formatGitAge(age time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age/time.Minute))
+	case age < day:
+		return fmt.Sprintf("%dh", int(age/time.Hour))
+	case age < 7*day:
+		return fmt.Sprintf("%dd", int(age/day))
+	case age < 365*day:
+		return fmt.Sprintf("%dw", int(age/(7*day)))
+	default:
+		return fmt.Sprintf("%dy", int(age/(365*day)))
+	}
+}
+
+var trailerRegex = regexp.MustCompile(`^([A-Za-z0-9-]+):\s*(.*)$`)
+
+// parseGitTrailers returns the trailers in the last paragraph of a commit
+// message, or nothing if that paragraph isn't made of trailers only
+func parseGitTrailers(message string) map[string]string {
+	trailers := map[string]string{}
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	if len(paragraphs) < 2 {
+		return trailers
+	}
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		match := trailerRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			return map[string]string{}
+		}
+		trailers[match[1]] = match[2]
+	}
+	return trailers
+}
+
+// gerritChangeIDRegex matches the Change-Id gerrit generates, so anything else
+// in the trailer never reaches the prompt
+var gerritChangeIDRegex = regexp.MustCompile(`^I[0-9a-f]+$`)
+
+// gerritChangeID returns the abbreviated Change-Id trailer of a commit message,
+// or an empty string if it has no valid one
+func gerritChangeID(message string) string {
+	changeID := parseGitTrailers(message)["Change-Id"]
+	if !gerritChangeIDRegex.MatchString(changeID) {
+		return ""
+	}
+	if len(changeID) > 8 {
+		changeID = changeID[:8]
+	}
+	return changeID
+}
+
+// getGerritChangeID returns the abbreviated Change-Id trailer of the HEAD
+// commit, or an empty string if it has none
+func getGerritChangeID() string {
+	out, err := runGitCommand("git", "log", "-1", "--format=%B")
+	if err != nil {
+		return ""
+	}
+	return gerritChangeID(out)
 }
 
 func parseGitStats(status []string) repoStats {
@@ -142,6 +825,8 @@ func parseGitStats(status []string) repoStats {
 				switch code {
 				case "??":
 					stats.untracked++
+				case "!!":
+					// ignored files are counted by gitIgnoredModifiedCount
 				case "DD", "AU", "UD", "UA", "DU", "AA", "UU":
 					stats.conflicted++
 				default:
@@ -159,6 +844,87 @@ func parseGitStats(status []string) repoStats {
 	return stats
 }
 
+// gitStatusLines splits the output of git status into the lines of porcelain
+// v1, converting porcelain v2 when -git-submodule-pointers asked for it
+func gitStatusLines(p *powerline, out string) []string {
+	lines := strings.Split(out, "\n")
+	if p.cfg.GitSubmodulePointers {
+		return porcelainV2ToV1(lines)
+	}
+	return lines
+}
+
+// porcelainV2ToV1 converts git status --porcelain=v2 -b output to porcelain v1
+// lines. Submodules whose work tree only has changes inside them are dropped,
+// the ones whose commit changed are staged or not staged like any other file.
+func porcelainV2ToV1(lines []string) []string {
+	var oid, head, upstream, ab string
+	var hasAB bool
+	entries := []string{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "# branch.oid "):
+			oid = strings.TrimPrefix(line, "# branch.oid ")
+		case strings.HasPrefix(line, "# branch.head "):
+			head = strings.TrimPrefix(line, "# branch.head ")
+		case strings.HasPrefix(line, "# branch.upstream "):
+			upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			ab, hasAB = strings.TrimPrefix(line, "# branch.ab "), true
+		case (fields[0] == "1" || fields[0] == "2") && len(fields) > 3:
+			code := []byte(fields[1])
+			// S<c><m><u> describes a submodule, c is C if its commit changed
+			if strings.HasPrefix(fields[2], "S") && fields[2][1] != 'C' {
+				code[1] = '.'
+			}
+			code = bytes.Replace(code, []byte("."), []byte(" "), -1)
+			if string(code) != "  " {
+				entries = append(entries, string(code)+" "+fields[len(fields)-1])
+			}
+		case fields[0] == "u" && len(fields) > 1:
+			entries = append(entries, fields[1]+" "+fields[len(fields)-1])
+		case fields[0] == "?":
+			entries = append(entries, "?? "+line[2:])
+		case fields[0] == "!":
+			entries = append(entries, "!! "+line[2:])
+		}
+	}
+
+	header := ""
+	switch {
+	case oid == "" && head == "":
+	case oid == "(initial)":
+		header = "## No commits yet on " + head
+	case head == "(detached)":
+		header = "## HEAD (no branch)"
+	default:
+		header = "## " + head
+		if upstream != "" {
+			header += "..." + upstream
+			var ahead, behind int
+			if !hasAB {
+				header += " [gone]"
+			} else if ab == "+? -?" {
+				header += " [different]"
+			} else if _, err := fmt.Sscanf(ab, "+%d -%d", &ahead, &behind); err == nil && ahead+behind > 0 {
+				var counts []string
+				if ahead > 0 {
+					counts = append(counts, fmt.Sprintf("ahead %d", ahead))
+				}
+				if behind > 0 {
+					counts = append(counts, fmt.Sprintf("behind %d", behind))
+				}
+				header += " [" + strings.Join(counts, ", ") + "]"
+			}
+		}
+	}
+	return append([]string{header}, entries...)
+}
+
 func repoRoot(path string) (string, error) {
 	out, err := runGitCommand("git", "rev-parse", "--show-toplevel")
 	if err != nil {
@@ -167,6 +933,77 @@ func repoRoot(path string) (string, error) {
 	return strings.TrimSpace(out), nil
 }
 
+var submoduleSectionRegex = regexp.MustCompile(`(?m)^\s*\[submodule\s`)
+
+// countSubmodules counts the submodules registered in the .gitmodules file of root
+func countSubmodules(root string) int {
+	gitmodules, err := ioutil.ReadFile(filepath.Join(root, ".gitmodules"))
+	if err != nil {
+		return 0
+	}
+	return len(submoduleSectionRegex.FindAll(gitmodules, -1))
+}
+
+const gitSubmoduleTimeout = 500 * time.Millisecond
+
+// gitSubmoduleStats sums the changes inside all submodules of the repository,
+// including nested ones, as far as it gets before ctx is done
+func gitSubmoduleStats(ctx context.Context) repoStats {
+	stats := repoStats{}
+	out, err := runGitCommandContext(ctx, "git", "submodule", "foreach", "--quiet", "--recursive", `echo "$toplevel/$sm_path"`)
+	if err != nil {
+		return stats
+	}
+	for _, submodule := range strings.Split(strings.TrimSpace(out), "\n") {
+		if submodule == "" {
+			continue
+		}
+		// nested submodules are listed on their own
+		status, err := runGitCommandContext(ctx, "git", "-C", submodule, "status", "--porcelain", "-b", "--ignore-submodules")
+		if ctx.Err() != nil {
+			return stats
+		}
+		if err != nil {
+			continue
+		}
+		submoduleStats := parseGitStats(strings.Split(status, "\n"))
+		stats.untracked += submoduleStats.untracked
+		stats.notStaged += submoduleStats.notStaged
+		stats.staged += submoduleStats.staged
+		stats.conflicted += submoduleStats.conflicted
+	}
+	return stats
+}
+
+var looseObjectRegex = regexp.MustCompile(`^[0-9a-f]{38}$`)
+
+// gitGCNeeded estimates whether the repository the current directory is in
+// has more than threshold loose objects the way git gc --auto does: by only
+// counting the objects in objects/17, which holds about 1/256 of them
+func gitGCNeeded(cwd string, threshold int) (bool, error) {
+	out, err := runGitCommand("git", "rev-parse", "--git-path", "objects")
+	if err != nil {
+		return false, err
+	}
+	objects := strings.TrimSpace(out)
+	if !filepath.IsAbs(objects) {
+		objects = filepath.Join(cwd, objects)
+	}
+	files, err := ioutil.ReadDir(filepath.Join(objects, "17"))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	count := 0
+	for _, file := range files {
+		if looseObjectRegex.MatchString(file.Name()) {
+			count++
+		}
+	}
+	return count > (threshold+255)/256, nil
+}
+
 func indexSize(root string) (int64, error) {
 	fileInfo, err := os.Stat(path.Join(root, ".git", "index"))
 	if err != nil {
@@ -176,38 +1013,451 @@ func indexSize(root string) (int64, error) {
 	return fileInfo.Size(), nil
 }
 
-func segmentGit(p *powerline) []pwl.Segment {
-	repoRoot, err := repoRoot(p.cwd)
+// gitConfigFalse reports whether a git config value turns a setting off, a
+// key without a value turns it on
+func gitConfigFalse(value string) bool {
+	switch strings.ToLower(value) {
+	case "false", "no", "off", "0":
+		return true
+	}
+	return false
+}
+
+// gitFSMonitorEnabled reports whether the repository has both a filesystem
+// monitor (core.fsmonitor) and the untracked cache (core.untrackedCache, which
+// feature.manyFiles turns on by default). Together they let git status find
+// changed and untracked files without scanning the work tree.
+func gitFSMonitorEnabled() bool {
+	out, err := runGitCommand("git", "config", "--get-regexp", `^(core\.fsmonitor|core\.untrackedcache|feature\.manyfiles)$`)
 	if err != nil {
-		return []pwl.Segment{}
+		return false
+	}
+	values := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) == 2 {
+			values[fields[0]] = fields[1]
+		} else {
+			values[fields[0]] = ""
+		}
 	}
 
-	if len(p.ignoreRepos) > 0 && p.ignoreRepos[repoRoot] {
-		return []pwl.Segment{}
+	fsmonitor, found := values["core.fsmonitor"]
+	if !found || gitConfigFalse(fsmonitor) {
+		return false
 	}
+	if untrackedCache, found := values["core.untrackedcache"]; found {
+		return !gitConfigFalse(untrackedCache)
+	}
+	manyFiles, found := values["feature.manyfiles"]
+	return found && !gitConfigFalse(manyFiles)
+}
 
+// gitStatusArgs returns the arguments for git status. Untracked files are
+// skipped for large indexes unless a filesystem monitor makes them cheap.
+func gitStatusArgs(p *powerline, fsmonitor bool) []string {
+	porcelain, ignoreSubmodules := "--porcelain", "--ignore-submodules"
+	if p.cfg.GitSubmodulePointers {
+		// only changes to the commit a submodule points to, which only
+		// porcelain v2 tells apart from other changes to it
+		porcelain, ignoreSubmodules = "--porcelain=v2", "--ignore-submodules=dirty"
+	}
 	args := []string{
-		"status", "--porcelain", "-b", "--ignore-submodules",
+		porcelain, "-b", ignoreSubmodules,
 	}
 
-	if p.cfg.GitAssumeUnchangedSize > 0 {
+	untrackedFiles := ""
+	if p.cfg.GitUntrackedDirAsSingle {
+		untrackedFiles = "-unormal"
+	}
+	if p.cfg.GitAssumeUnchangedSize > 0 && !fsmonitor {
 		indexSize, _ := indexSize(p.cwd)
 		if indexSize > (p.cfg.GitAssumeUnchangedSize * 1024) {
-			args = append(args, "-uno")
+			untrackedFiles = "-uno"
+		}
+	}
+	if untrackedFiles != "" {
+		args = append(args, untrackedFiles)
+	}
+	if p.cfg.GitAheadBehindCap > 0 {
+		args = append(args, "--no-ahead-behind")
+	}
+	return args
+}
+
+// hideGitInHome reports whether git segments are suppressed because the
+// current directory is the home directory itself (not one of its children)
+func hideGitInHome(p *powerline) bool {
+	home := os.Getenv(homeEnvName())
+	if home == "" {
+		home = p.userInfo.HomeDir
+	}
+	if !p.cfg.GitHideInHome || home == "" {
+		return false
+	}
+	return filepath.Clean(p.cwd) == filepath.Clean(home)
+}
+
+// insideGitDir returns the git directory containing the current directory, and
+// whether the current directory is inside one at all
+func insideGitDir() (string, bool) {
+	out, err := runGitCommand("git", "rev-parse", "--is-inside-git-dir", "--absolute-git-dir")
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || lines[0] != "true" {
+		return "", false
+	}
+	return lines[1], true
+}
+
+// getGitRepoType returns the kind of repository the current directory is in,
+// in order of precedence: "bare", "submodule", "worktree" for a linked
+// worktree, "sparse" for a sparse checkout, or "" for a regular repository
+func getGitRepoType(cwd string) string {
+	if out, _ := runGitCommand("git", "rev-parse", "--is-bare-repository"); strings.TrimSpace(out) == "true" {
+		return "bare"
+	}
+	if out, _ := runGitCommand("git", "rev-parse", "--show-superproject-working-tree"); strings.TrimSpace(out) != "" {
+		return "submodule"
+	}
+	if out, err := runGitCommand("git", "rev-parse", "--git-dir", "--git-common-dir"); err == nil {
+		dirs := strings.Split(strings.TrimSpace(out), "\n")
+		for i, dir := range dirs {
+			if !filepath.IsAbs(dir) {
+				dirs[i] = filepath.Join(cwd, dir)
+			}
+		}
+		if len(dirs) == 2 && filepath.Clean(dirs[0]) != filepath.Clean(dirs[1]) {
+			return "worktree"
+		}
+	}
+	if out, _ := runGitCommand("git", "config", "--bool", "core.sparseCheckout"); strings.TrimSpace(out) == "true" {
+		return "sparse"
+	}
+	return ""
+}
+
+// gitBranchDisplayName returns the part of a branch name that is shown, the
+// full name is still used for everything else
+func gitBranchDisplayName(p *powerline, branch string) string {
+	if p.cfg.GitBranchBasenameOnly {
+		return path.Base(branch)
+	}
+	return branch
+}
+
+// formatGitBranch prefixes branch with the branch symbol and, if enabled, the
+// repository type icon
+func formatGitBranch(p *powerline, branch string) string {
+	branch = escapeEvalSafe(p, branch)
+	if len(p.symbols.RepoBranch) > 0 {
+		branch = fmt.Sprintf("%s %s", p.symbols.RepoBranch, branch)
+	}
+	if p.cfg.GitShowRepoType {
+		icons := map[string]string{
+			"bare":      p.symbols.RepoTypeBare,
+			"submodule": p.symbols.RepoTypeSubmodule,
+			"worktree":  p.symbols.RepoTypeWorktree,
+			"sparse":    p.symbols.RepoTypeSparse,
+		}
+		if icon := icons[getGitRepoType(p.cwd)]; icon != "" {
+			branch = fmt.Sprintf("%s %s", icon, branch)
 		}
 	}
+	return branch
+}
 
-	out, err := runGitCommand("git", args...)
+// gitRepoConfig reads the powerline.* keys from the config file of gitDir
+// without running git, lowercasing the names like git does. Worktrees use the
+// config of the repository they belong to.
+func gitRepoConfig(gitDir string) (map[string]string, error) {
+	if common, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		gitDir = commonDir
+	}
+	file, err := os.Open(filepath.Join(gitDir, "config"))
 	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	config := gitconfig.New()
+	if err := gitconfig.NewDecoder(file).Decode(config); err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	if !config.HasSection("powerline") {
+		return values, nil
+	}
+	// the last value of a key wins, like with git config --get
+	for _, option := range config.Section("powerline").Options {
+		value := option.Value
+		if value == "" {
+			// a key without a value is a true boolean
+			value = "true"
+		}
+		values["powerline."+strings.ToLower(option.Key)] = value
+	}
+	return values, nil
+}
+
+// applyGitRepoConfig overrides the global config with the powerline.* keys of
+// a repository, so teams can commit prompt settings to a repository:
+//
+//	powerline.gitMode       like -git-mode
+//	powerline.disableStats  like -git-disable-stats
+//	powerline.hideStats     true disables all git statuses, including the
+//	                        optional ignored, submodule and stash counts
+func applyGitRepoConfig(cfg Config, values map[string]string) Config {
+	for key, value := range values {
+		switch key {
+		case "powerline.gitmode":
+			cfg.GitMode = value
+		case "powerline.disablestats":
+			cfg.GitDisableStats = strings.Split(value, ",")
+		case "powerline.hidestats":
+			if hide, err := strconv.ParseBool(value); err == nil && hide {
+				cfg.GitDisableStats = []string{"ahead", "behind", "staged", "notStaged", "untracked", "conflicted", "stashed"}
+				cfg.GitShowIgnoredCount = false
+				cfg.GitShowSubmoduleCount = false
+				cfg.GitRecurseSubmodules = false
+				cfg.GitStashClassify = false
+				cfg.GitStashFileCount = false
+			}
+		}
+	}
+	return cfg
+}
+
+// gitSkipsFilesystem reports whether fsType is one of the filesystems git
+// status is skipped on
+func gitSkipsFilesystem(fsType string, skipped []string) bool {
+	for _, name := range skipped {
+		if name = strings.TrimSpace(name); name != "" && strings.EqualFold(name, fsType) {
+			return true
+		}
+	}
+	return false
+}
+
+// findGitDir looks for the .git directory of the repository dir is in without
+// running git, following the gitdir file of worktrees and submodules
+func findGitDir(dir string) (string, bool) {
+	for {
+		candidate := filepath.Join(dir, ".git")
+		if info, err := os.Stat(candidate); err == nil {
+			if info.IsDir() {
+				return candidate, true
+			}
+			content, err := ioutil.ReadFile(candidate)
+			if err != nil || !strings.HasPrefix(string(content), "gitdir: ") {
+				return "", false
+			}
+			gitDir := strings.TrimSpace(strings.TrimPrefix(string(content), "gitdir: "))
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+			return gitDir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// readGitHead returns the branch HEAD of gitDir points to, or the short hash
+// of the commit and true if it is detached
+func readGitHead(gitDir string) (string, bool, error) {
+	content, err := ioutil.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", false, err
+	}
+	head := strings.TrimSpace(string(content))
+	if strings.HasPrefix(head, "ref: ") {
+		branch, _ := shortSymbolicRef(strings.TrimPrefix(head, "ref: "))
+		return branch, false, nil
+	}
+	if len(head) > 7 {
+		head = head[:7]
+	}
+	return head, true, nil
+}
+
+// gitIndexLocked reports whether another git process holds the index lock of
+// the repository dir is in
+func gitIndexLocked(dir string) bool {
+	gitDir, found := findGitDir(dir)
+	return found && pathExists(filepath.Join(gitDir, "index.lock"))
+}
+
+// bisectLogRegex matches the comments of a BISECT_LOG recording a commit being
+// marked, which git writes whether it was marked by git bisect start or not
+var bisectLogRegex = regexp.MustCompile(`^# (\w+): \[([0-9a-f]+)\]`)
+
+// bisectRefs returns the last commit marked bad and the commits marked good in
+// a BISECT_LOG, also accepting the new and old terms
+func bisectRefs(log string) (string, []string) {
+	var bad string
+	var good []string
+	for _, line := range strings.Split(log, "\n") {
+		match := bisectLogRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		switch match[1] {
+		case "bad", "new":
+			bad = match[2]
+		case "good", "old":
+			good = append(good, match[2])
+		}
+	}
+	return bad, good
+}
+
+// gitBisectState reports whether gitDir is in the middle of a bisect, and how
+// many candidates for the first bad commit remain, or -1 while no good and bad
+// commits are known yet
+func gitBisectState(gitDir string) (bool, int) {
+	if !pathExists(filepath.Join(gitDir, "BISECT_START")) && !pathExists(filepath.Join(gitDir, "BISECT_LOG")) {
+		return false, -1
+	}
+	log, err := ioutil.ReadFile(filepath.Join(gitDir, "BISECT_LOG"))
+	if err != nil {
+		return true, -1
+	}
+	bad, good := bisectRefs(string(log))
+	if bad == "" || len(good) == 0 {
+		return true, -1
+	}
+	out, err := runGitCommand("git", append([]string{"rev-list", "--count", bad, "--not"}, good...)...)
+	if err != nil {
+		return true, -1
+	}
+	remaining, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return true, -1
+	}
+	return true, remaining
+}
+
+// segmentGitFromHead shows the branch read straight from .git/HEAD, for
+// filesystems too slow to run git status on
+func segmentGitFromHead(p *powerline) []pwl.Segment {
+	gitDir, found := findGitDir(p.cwd)
+	if !found || p.ignoreRepos[filepath.Dir(gitDir)] {
 		return []pwl.Segment{}
 	}
+	branch, detached, err := readGitHead(gitDir)
+	if err != nil {
+		return []pwl.Segment{}
+	}
+	if detached {
+		branch = fmt.Sprintf("%s %s", p.symbols.RepoDetached, branch)
+	} else {
+		branch = gitBranchDisplayName(p, branch)
+	}
+	return []pwl.Segment{{
+		Name:       "git-branch",
+		Content:    formatGitBranch(p, branch),
+		Foreground: p.theme.RepoCleanFg,
+		Background: p.theme.RepoCleanBg,
+	}}
+}
+
+// segmentGitBranchOnly renders just the branch, for when the repository
+// status is unavailable
+func segmentGitBranchOnly(p *powerline) []pwl.Segment {
+	branch := p.gitBranch()
+	if branch == "" {
+		return []pwl.Segment{}
+	}
+	if branch == "HEAD" {
+		branch = getGitDetachedBranch(p)
+	} else {
+		branch = gitBranchDisplayName(p, branch)
+	}
+	branch = formatGitBranch(p, branch)
+	return []pwl.Segment{{
+		Name:       "git-branch",
+		Content:    branch,
+		Foreground: p.theme.RepoCleanFg,
+		Background: p.theme.RepoCleanBg,
+	}}
+}
+
+func segmentGit(p *powerline) []pwl.Segment {
+	if hideGitInHome(p) {
+		return []pwl.Segment{}
+	}
+
+	if len(p.cfg.GitSkipOnFS) > 0 {
+		if fsType, err := filesystemType(p.cwd); err == nil && gitSkipsFilesystem(fsType, p.cfg.GitSkipOnFS) {
+			p.debug(fmt.Sprintf("git status skipped on %s filesystem", fsType))
+			return segmentGitFromHead(p)
+		}
+	}
+
+	repoRoot, err := repoRoot(p.cwd)
+	if err != nil {
+		// bare repositories have no work tree, but still have a branch
+		if p.cfg.GitShowRepoType && getGitRepoType(p.cwd) == "bare" {
+			return segmentGitBranchOnly(p)
+		}
+		// neither is there one inside the .git directory of a repository
+		if gitDir, inside := insideGitDir(); inside && !p.ignoreRepos[filepath.Dir(gitDir)] {
+			segments := segmentGitBranchOnly(p)
+			if len(segments) > 0 {
+				segments[0].Content = fmt.Sprintf("%s %s", p.symbols.RepoInGitDir, segments[0].Content)
+			}
+			return segments
+		}
+		return []pwl.Segment{}
+	}
+
+	if len(p.ignoreRepos) > 0 && p.ignoreRepos[repoRoot] {
+		return []pwl.Segment{}
+	}
+
+	if p.cfg.GitDetectLock && gitIndexLocked(p.cwd) {
+		// status would wait for the other git process, or be outdated
+		p.debug("git index is locked, skipping git status")
+		segments := segmentGitBranchOnly(p)
+		if len(segments) > 0 {
+			segments[0].Content = fmt.Sprintf("%s %s", segments[0].Content, p.symbols.RepoIndexLocked)
+		}
+		return segments
+	}
+
+	fsmonitor := p.cfg.GitFSMonitor && gitFSMonitorEnabled()
+	p.debug(fmt.Sprintf("git fsmonitor in use: %v", fsmonitor))
+
+	start := time.Now()
+	out, err := gitStatus(gitStatusArgs(p, fsmonitor)...)
+	p.debugTiming("git status", start)
+	status := gitStatusLines(p, out)
+	if err != nil {
+		// git status can fail on a single unreadable file after having
+		// reported everything else, so use whatever output we got
+		p.debug("git status failed: " + err.Error())
+		if !strings.HasPrefix(status[0], "## ") {
+			return segmentGitBranchOnly(p)
+		}
+	}
 
-	status := strings.Split(out, "\n")
 	stats := parseGitStats(status)
 	branchInfo := parseGitBranchInfo(status)
-	var branch string
+	var branch, remote, pushDestination string
+	var noUpstream, symbolicHead bool
 
-	if branchInfo["local"] != "" {
+	if unborn := unbornBranchRegex.FindStringSubmatch(status[0]); unborn != nil {
+		branch = fmt.Sprintf("%s %s", gitBranchDisplayName(p, unborn[1]), p.symbols.RepoEmpty)
+	} else if branchInfo["local"] != "" {
 		ahead, _ := strconv.ParseInt(branchInfo["ahead"], 10, 32)
 		stats.ahead = int(ahead)
 
@@ -215,38 +1465,115 @@ func segmentGit(p *powerline) []pwl.Segment {
 		stats.behind = int(behind)
 
 		branch = branchInfo["local"]
+		// git status shows the full ref if HEAD points outside refs/heads
+		branch, symbolicHead = shortSymbolicRef(branch)
+		upstreamRef, upstream := branchInfo["remote"], parseGitUpstreamState(branchInfo)
+		if !symbolicHead {
+			upstreamRef, upstream = resolveGitUpstream(p, branch, branchInfo)
+		}
+		stats.upstream = upstream == gitUpstreamTracking || upstream == gitUpstreamFallback
+		noUpstream = p.cfg.GitShowNoUpstream && upstream == gitUpstreamNone && !symbolicHead
+
+		// with --no-ahead-behind, git status only says whether the branches
+		// differ, and it doesn't compare to a fallback upstream at all, so
+		// count the commits ourselves with a limit
+		if branchInfo["different"] != "" || upstream == gitUpstreamFallback {
+			start = time.Now()
+			stats.ahead, stats.behind, _ = gitAheadBehindLimited("HEAD", upstreamRef, p.cfg.GitAheadBehindCap)
+			p.debugTiming("git ahead/behind", start)
+		}
+
+		if p.cfg.GitShowPushAheadBehind {
+			start = time.Now()
+			if target := getGitPushTarget(branch); target != "" && target != upstreamRef {
+				stats.pushAhead, stats.pushBehind, _ = gitAheadBehindLimited(branch, target, p.cfg.GitAheadBehindCap)
+			}
+			p.debugTiming("git push ahead/behind", start)
+		}
+		if p.cfg.GitShowBehindDefault {
+			if target := getGitDefaultBranch(); target != "" && target != upstreamRef {
+				_, stats.behindDefault, _ = gitAheadBehindLimited(branch, target, p.cfg.GitAheadBehindCap)
+			}
+		}
+		if p.cfg.GitPushTargetBadge {
+			pushDestination = getGitPushDestination(branch)
+		}
+		if p.cfg.GitShowRemoteName && stats.upstream {
+			remote = getGitRemoteName(branch)
+		}
+		branch = gitBranchDisplayName(p, branch)
 	} else {
 		branch = getGitDetachedBranch(p)
+		if p.cfg.GitDetachedAheadBehind {
+			start = time.Now()
+			if upstream := getGitDetachedUpstream(); upstream != "" {
+				stats.ahead, stats.behind, err = gitAheadBehindLimited("HEAD", upstream, p.cfg.GitAheadBehindCap)
+				stats.upstream = err == nil
+			}
+			p.debugTiming("git detached ahead/behind", start)
+		}
 	}
 
-	if len(p.symbols.RepoBranch) > 0 {
-		branch = fmt.Sprintf("%s %s", p.symbols.RepoBranch, branch)
+	branch = formatGitBranch(p, branch)
+	if symbolicHead {
+		branch = fmt.Sprintf("%s %s", branch, p.symbols.RepoSymbolicRef)
+	}
+	if noUpstream {
+		branch = fmt.Sprintf("%s %s", branch, p.symbols.RepoLocal)
+	}
+	if p.cfg.GitShowGerrit {
+		if changeID := getGerritChangeID(); changeID != "" {
+			branch = fmt.Sprintf("%s %s", branch, changeID)
+		}
+	}
+
+	if p.cfg.GitRecurseSubmodules {
+		start = time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), gitSubmoduleTimeout)
+		submoduleStats := gitSubmoduleStats(ctx)
+		cancel()
+		if submoduleStats.dirty() {
+			stats.untracked += submoduleStats.untracked
+			stats.notStaged += submoduleStats.notStaged
+			stats.staged += submoduleStats.staged
+			stats.conflicted += submoduleStats.conflicted
+			branch = fmt.Sprintf("%s %s", branch, p.symbols.RepoRecursive)
+		}
+		p.debugTiming("git submodule status", start)
 	}
 
+	dirty := stats.dirtyBranch(p)
 	var foreground, background uint8
-	if stats.dirty() {
+	if dirty {
 		foreground = p.theme.RepoDirtyFg
 		background = p.theme.RepoDirtyBg
 	} else {
 		foreground = p.theme.RepoCleanFg
 		background = p.theme.RepoCleanBg
 	}
+	if p.cfg.GitBranchColorBySync {
+		if symbol, syncForeground, syncBackground := stats.syncState(p); symbol != "" {
+			foreground, background = syncForeground, syncBackground
+		}
+	}
 
-	segments := []pwl.Segment{{
-		Name:       "git-branch",
-		Content:    branch,
-		Foreground: foreground,
-		Background: background,
-	}}
+	disabledStats := p.cfg.GitDisableStats
+	if p.cfg.GitMode == "sync" {
+		// like fancy, but only the branch and how it relates to upstream
+		disabledStats = append([]string{"staged", "notStaged", "untracked", "conflicted", "stashed"}, disabledStats...)
+	}
 
 	stashEnabled := true
-	for _, stat := range p.cfg.GitDisableStats {
+	for _, stat := range disabledStats {
 		// "ahead, behind, staged, notStaged, untracked, conflicted, stashed"
 		switch stat {
 		case "ahead":
 			stats.ahead = 0
+			stats.pushAhead = 0
 		case "behind":
 			stats.behind = 0
+			stats.pushBehind = 0
+			stats.behindDefault = 0
 		case "staged":
 			stats.staged = 0
 		case "notStaged":
@@ -262,22 +1589,200 @@ func segmentGit(p *powerline) []pwl.Segment {
 	}
 
 	if stashEnabled {
+		start = time.Now()
 		out, err = runGitCommand("git", "rev-list", "-g", "refs/stash")
 		if err == nil {
 			stats.stashed = strings.Count(out, "\n")
 		}
+		if p.cfg.GitStashClassify && stats.stashed > 0 {
+			if out, err := runGitCommand("git", "log", "-g", "--format=%gs", "refs/stash"); err == nil {
+				if wip, named, ok := classifyGitStashes(out); ok {
+					stats.stashedWIP, stats.stashedNamed = wip, named
+				}
+			}
+		}
+		if p.cfg.GitStashFileCount && stats.stashed > 0 {
+			stats.stashFiles, _ = gitStashFileCount()
+		}
+		p.debugTiming("git stash", start)
+	}
+
+	if p.cfg.GitShowSubmoduleCount && p.cfg.GitMode != "sync" {
+		stats.submodules = countSubmodules(repoRoot)
+	}
+	if p.cfg.GitShowIgnoredCount && p.cfg.GitMode != "sync" {
+		stats.ignored = gitIgnoredModifiedCount()
+	}
+
+	if p.cfg.GitHideWhenClean && !dirty && !stats.any() {
+		return []pwl.Segment{}
+	}
+
+	segments := []pwl.Segment{{
+		Name:       "git-branch",
+		Content:    branch,
+		Foreground: foreground,
+		Background: background,
+	}}
+
+	if gitDir, found := findGitDir(p.cwd); found {
+		if bisecting, remaining := gitBisectState(gitDir); bisecting {
+			content := p.symbols.RepoBisect
+			if remaining >= 0 {
+				content = fmt.Sprintf("%s %d left", content, remaining)
+			}
+			segments = append(segments, pwl.Segment{
+				Name:       "git-operation",
+				Content:    content,
+				Foreground: p.theme.GitOperationFg,
+				Background: p.theme.GitOperationBg,
+			})
+		}
+	}
+
+	if p.cfg.GitShowCommitSubject {
+		if subject := getGitCommitSubject(p.cfg.GitCommitSubjectMaxLength); subject != "" {
+			segments = append(segments, pwl.Segment{
+				Name:       "git-subject",
+				Content:    escapePromptText(p, subject),
+				Foreground: p.theme.GitCommitSubjectFg,
+				Background: p.theme.GitCommitSubjectBg,
+			})
+		}
+	}
+
+	if remote != "" {
+		segments = append(segments, pwl.Segment{
+			Name:       "git-remote",
+			Content:    fmt.Sprintf("%s %s", p.symbols.RepoRemote, escapeEvalSafe(p, remote)),
+			Foreground: p.theme.GitRemoteFg,
+			Background: p.theme.GitRemoteBg,
+		})
 	}
 
-	if p.cfg.GitMode == "simple" {
-		if stats.any() {
+	if p.cfg.GitShowRemoteCount {
+		if count := gitRemoteCount(); count > 1 {
+			segments = append(segments, pwl.Segment{
+				Name:       "git-remotes",
+				Content:    fmt.Sprintf("%d%s", count, p.symbols.RepoRemotes),
+				Foreground: p.theme.GitRemoteCountFg,
+				Background: p.theme.GitRemoteCountBg,
+			})
+		}
+	}
+
+	if p.cfg.GitShowUnpushedIndicator {
+		if pushed, ok := gitHeadPushed(); ok && !pushed {
+			segments = append(segments, pwl.Segment{
+				Name:       "git-unpushed",
+				Content:    p.symbols.RepoUnpushed,
+				Foreground: p.theme.GitUnpushedFg,
+				Background: p.theme.GitUnpushedBg,
+			})
+		}
+	}
+
+	if p.cfg.GitShowGCHint {
+		if needed, err := gitGCNeeded(p.cwd, p.cfg.GitGCHintThreshold); err == nil && needed {
+			segments = append(segments, pwl.Segment{
+				Name:       "git-gc",
+				Content:    p.symbols.RepoGCNeeded,
+				Foreground: p.theme.GitGCHintFg,
+				Background: p.theme.GitGCHintBg,
+			})
+		}
+	}
+
+	if p.cfg.GitShowCommitAge {
+		if committed, err := getGitCommitTime(p.cfg.GitAgeDate); err == nil {
+			segments = append(segments, pwl.Segment{
+				Name:       "git-age",
+				Content:    formatGitAge(time.Since(committed)),
+				Foreground: p.theme.GitAgeFg,
+				Background: p.theme.GitAgeBg,
+			})
+		}
+	}
+
+	if p.cfg.GitShowMergeBase {
+		if mergeBase, err := getGitMergeBase(p.cfg.GitMergeBaseRef); err == nil && mergeBase != "" {
+			segments = append(segments, pwl.Segment{
+				Name:       "git-merge-base",
+				Content:    mergeBase,
+				Foreground: p.theme.GitMergeBaseFg,
+				Background: p.theme.GitMergeBaseBg,
+			})
+		}
+	}
+
+	if pushDestination != "" {
+		segments = append(segments, pwl.Segment{
+			Name:       "git-push-target",
+			Content:    fmt.Sprintf("%s %s", p.symbols.RepoPushTarget, escapeEvalSafe(p, pushDestination)),
+			Foreground: p.theme.GitPushTargetFg,
+			Background: p.theme.GitPushTargetBg,
+		})
+	}
+
+	showStats := stats.any() || stats.submodules > 0 || stats.ignored > 0 || (p.cfg.GitSyncGlyphMode && stats.upstream)
+	if p.cfg.GitMode == "simple" || p.cfg.GitMode == "counts" {
+		if showStats && p.cfg.GitStatsBeforeBranch {
+			segments[0].Content = stats.GitSymbols(p) + " " + segments[0].Content
+		} else if showStats {
 			segments[0].Content += " " + stats.GitSymbols(p)
 		}
 	} else if p.cfg.GitMode == "compact" {
-		if stats.any() {
+		if showStats && p.cfg.GitStatsBeforeBranch {
+			// compact symbols start with a space meant to follow the branch
+			segments[0].Content = strings.TrimPrefix(stats.GitSymbols(p), " ") + " " + segments[0].Content
+		} else if showStats {
 			segments[0].Content += stats.GitSymbols(p)
 		}
+	} else if p.cfg.GitMode == "dot" {
+		if p.cfg.GitStatsBeforeBranch {
+			segments = append(stats.GitDotSegments(p), segments...)
+		} else {
+			segments = append(segments, stats.GitDotSegments(p)...)
+		}
+	} else if p.cfg.GitMode == "micro" {
+		if p.cfg.GitStatsBeforeBranch {
+			segments = append(stats.GitMicroSegments(p), segments...)
+		} else {
+			segments = append(segments, stats.GitMicroSegments(p)...)
+		}
 	} else { // fancy
-		segments = append(segments, stats.GitSegments(p)...)
+		statSegments := stats.GitSegments(p)
+		if p.cfg.GitStatsThinSeparator && len(statSegments) > 0 {
+			first, second := &segments[0], &statSegments[0]
+			if p.cfg.GitStatsBeforeBranch {
+				first, second = &statSegments[len(statSegments)-1], &segments[0]
+			}
+			// right prompts draw the separator before a segment, left
+			// prompts after it
+			if p.isRightPrompt() {
+				second.Separator = p.symbols.SeparatorReverseThin
+				second.SeparatorForeground = p.theme.SeparatorFg
+			} else {
+				first.Separator = p.symbols.SeparatorThin
+				first.SeparatorForeground = p.theme.SeparatorFg
+			}
+		}
+		if p.cfg.GitStatsBeforeBranch {
+			segments = append(statSegments, segments...)
+		} else {
+			segments = append(segments, statSegments...)
+		}
+	}
+
+	if p.cfg.GitShowNested {
+		if outer, outerBranch, ok := enclosingGitRepo(repoRoot); ok && !p.ignoreRepos[outer] {
+			segments = append([]pwl.Segment{{
+				Name:       "git-nested",
+				Content:    formatGitBranch(p, gitBranchDisplayName(p, outerBranch)),
+				Foreground: p.theme.GitNestedFg,
+				Background: p.theme.GitNestedBg,
+			}}, segments...)
+		}
 	}
 
 	return segments
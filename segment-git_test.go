@@ -0,0 +1,1613 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+func testPowerline(cfg Config) *powerline {
+	return &powerline{
+		cfg:     cfg,
+		theme:   cfg.Themes[cfg.Theme],
+		symbols: cfg.Modes[cfg.Mode],
+	}
+}
+
+func Test_gitMicro(t *testing.T) {
+	cfg := defaults
+	cfg.Theme = "low-contrast"
+	p := testPowerline(cfg)
+	tests := []struct {
+		name     string
+		stats    repoStats
+		dominant string
+		content  string
+		fg       uint8
+	}{
+		{"clean", repoStats{ahead: 2, stashed: 1}, "", "", 0},
+		{"only staged", repoStats{staged: 3}, "staged", "\u00B13", p.theme.GitStagedFg},
+		{"mostly modified", repoStats{staged: 1, notStaged: 4, untracked: 2}, "not-staged", "\u00B17", p.theme.GitNotStagedFg},
+		{"mostly untracked", repoStats{notStaged: 1, untracked: 5}, "untracked", "\u00B16", p.theme.GitUntrackedFg},
+		{"tie", repoStats{staged: 2, notStaged: 2}, "staged", "\u00B14", p.theme.GitStagedFg},
+		{"conflict wins", repoStats{notStaged: 9, conflicted: 1}, "conflicted", "\u00B110", p.theme.GitConflictedFg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.dominantChange(); got != tt.dominant {
+				t.Errorf("dominantChange() = %q, want %q", got, tt.dominant)
+			}
+			segments := tt.stats.GitMicroSegments(p)
+			if tt.content == "" {
+				if len(segments) != 0 {
+					t.Errorf("GitMicroSegments() = %+v, want none", segments)
+				}
+				return
+			}
+			if len(segments) != 1 || segments[0].Content != tt.content ||
+				segments[0].Foreground != tt.fg || segments[0].Background != p.theme.RepoDirtyBg {
+				t.Errorf("GitMicroSegments() = %+v, want %q in %d on %d", segments, tt.content, tt.fg, p.theme.RepoDirtyBg)
+			}
+		})
+	}
+}
+
+func Test_gitDot(t *testing.T) {
+	p := testPowerline(defaults)
+	tests := []struct {
+		name     string
+		stats    repoStats
+		severity string
+		bg       uint8
+	}{
+		{"clean", repoStats{upstream: true, stashed: 2}, "clean", p.theme.GitSyncedBg},
+		{"ahead", repoStats{ahead: 1}, "diverged", p.theme.GitSyncAheadBg},
+		{"behind", repoStats{behind: 3}, "diverged", p.theme.GitSyncAheadBg},
+		{"dirty", repoStats{ahead: 1, notStaged: 2}, "dirty", p.theme.GitNotStagedBg},
+		{"untracked", repoStats{untracked: 1}, "dirty", p.theme.GitNotStagedBg},
+		{"conflicts", repoStats{ahead: 1, staged: 4, conflicted: 1}, "conflicted", p.theme.GitConflictedBg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.dotSeverity(p); got != tt.severity {
+				t.Errorf("dotSeverity() = %q, want %q", got, tt.severity)
+			}
+			segments := tt.stats.GitDotSegments(p)
+			if len(segments) != 1 || segments[0].Content != p.symbols.RepoDot || segments[0].Background != tt.bg {
+				t.Errorf("GitDotSegments() = %+v, want a dot on %d", segments, tt.bg)
+			}
+		})
+	}
+}
+
+func Test_syncState(t *testing.T) {
+	p := testPowerline(defaults)
+	tests := []struct {
+		name  string
+		stats repoStats
+		want  string
+		bg    uint8
+	}{
+		{"ahead", repoStats{ahead: 2, upstream: true}, p.symbols.RepoSyncAhead, p.theme.GitSyncAheadBg},
+		{"behind", repoStats{behind: 3, upstream: true}, p.symbols.RepoSyncBehind, p.theme.GitSyncBehindBg},
+		{"diverged", repoStats{ahead: 2, behind: 3, upstream: true}, p.symbols.RepoSyncDiverged, p.theme.GitSyncDivergedBg},
+		{"synced", repoStats{upstream: true}, p.symbols.RepoSynced, p.theme.GitSyncedBg},
+		{"no upstream", repoStats{}, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, bg := tt.stats.syncState(p)
+			if got != tt.want || bg != tt.bg {
+				t.Errorf("syncState() = %q (bg %d), want %q (bg %d)", got, bg, tt.want, tt.bg)
+			}
+		})
+	}
+}
+
+func BenchmarkGitStatusUntracked(b *testing.B) {
+	if _, err := exec.LookPath("git"); err != nil {
+		b.Skip("git not available")
+	}
+	dir := b.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	runGitCommand("git", "init", "-q")
+	for i := 0; i < 2000; i++ {
+		path := filepath.Join(dir, "untracked", strconv.Itoa(i%20), strconv.Itoa(i))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte("x"), 0644)
+	}
+
+	for _, mode := range []string{"-uall", "-unormal"} {
+		b.Run(mode, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				out, _ := runGitCommand("git", "status", "--porcelain", "-b", "--ignore-submodules", mode)
+				parseGitStats(strings.Split(out, "\n"))
+			}
+		})
+	}
+}
+
+func Test_gitIgnoredModifiedCount(t *testing.T) {
+	newGitFixture(t, 1)
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if out, err := runGitCommand("git", args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	for name, content := range map[string]string{
+		".gitignore": "*.log\n",
+		"kept.log":   "kept\n",
+		"edited.log": "edited\n",
+	} {
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", "-f", ".gitignore", "kept.log", "edited.log")
+	git("commit", "-q", "-m", "whitelist logs")
+	if err := ioutil.WriteFile("edited.log", []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile("untracked.log", []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := gitIgnoredModifiedCount(); count != 1 {
+		t.Errorf("gitIgnoredModifiedCount() = %d, want 1", count)
+	}
+
+	stats := parseGitStats([]string{"## main", "!! untracked.log", " M edited.log"})
+	if stats.ignored != 0 || stats.notStaged != 1 || !stats.any() {
+		t.Errorf("parseGitStats() = %+v, want ignored entries skipped", stats)
+	}
+	if (repoStats{ignored: 1}).any() || (repoStats{ignored: 1}).dirty() {
+		t.Errorf("an ignored count alone made the repository dirty")
+	}
+}
+
+func Test_hideGitInHome(t *testing.T) {
+	home, found := os.LookupEnv(homeEnvName())
+	t.Cleanup(func() {
+		if found {
+			os.Setenv(homeEnvName(), home)
+		} else {
+			os.Unsetenv(homeEnvName())
+		}
+	})
+
+	cfg := defaults
+	cfg.GitHideInHome = true
+	p := testPowerline(cfg)
+	p.userInfo.HomeDir = "/home/me"
+
+	tests := []struct {
+		home string
+		cwd  string
+		want bool
+	}{
+		// without $HOME, the home directory of the user is used
+		{"", "/home/me", true},
+		{"", "/home/me/", true},
+		{"", "/home/me/project", false},
+		{"", "/home", false},
+		{"/srv/home", "/srv/home", true},
+		{"/srv/home", "/home/me", false},
+	}
+	for _, tt := range tests {
+		os.Setenv(homeEnvName(), tt.home)
+		p.cwd = tt.cwd
+		if got := hideGitInHome(p); got != tt.want {
+			t.Errorf("hideGitInHome(%q) with $HOME %q = %v, want %v", tt.cwd, tt.home, got, tt.want)
+		}
+	}
+}
+
+// newGitFixture creates a repository with the given number of commits on main,
+// tracking origin/main at the same commit, and changes into it
+func newGitFixture(t *testing.T, commits int) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(wd) })
+	os.Chdir(dir)
+
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if out, err := runGitCommand("git", args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("checkout", "-q", "-b", "main")
+	for i := 0; i < commits; i++ {
+		git("commit", "-q", "--allow-empty", "-m", strconv.Itoa(i))
+	}
+	git("config", "remote.origin.url", dir)
+	git("config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*")
+	git("config", "branch.main.remote", "origin")
+	git("config", "branch.main.merge", "refs/heads/main")
+	git("update-ref", "refs/remotes/origin/main", "main")
+	return dir
+}
+
+func Test_detachedAheadBehind(t *testing.T) {
+	newGitFixture(t, 5)
+	runGitCommand("git", "checkout", "-q", "HEAD~3")
+
+	upstream := getGitDetachedUpstream()
+	if upstream != "origin/main" {
+		t.Fatalf("getGitDetachedUpstream() = %q, want origin/main", upstream)
+	}
+	ahead, behind, err := gitAheadBehind("HEAD", upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ahead != 0 || behind != 3 {
+		t.Errorf("gitAheadBehind() = %d, %d, want 0, 3", ahead, behind)
+	}
+}
+
+func Test_gitAheadBehindCap(t *testing.T) {
+	dir := newGitFixture(t, 30)
+	runGitCommand("git", "update-ref", "refs/remotes/origin/main", "main~25")
+
+	ahead, behind, err := gitAheadBehindLimited("HEAD", "origin/main", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ahead != 11 || behind != 0 {
+		t.Errorf("gitAheadBehindLimited() = %d, %d, want the count capped at 11, 0", ahead, behind)
+	}
+	if ahead, _, _ := gitAheadBehindLimited("HEAD", "origin/main", 0); ahead != 25 {
+		t.Errorf("gitAheadBehindLimited() without a limit = %d, want 25", ahead)
+	}
+
+	cfg := defaults
+	cfg.GitMode = "compact"
+	cfg.GitAheadBehindCap = 0
+	p := testPowerline(cfg)
+	p.cwd = dir
+	if args := strings.Join(gitStatusArgs(p, false), " "); strings.Contains(args, "--no-ahead-behind") {
+		t.Errorf("gitStatusArgs() without a cap = %q, want git status to count", args)
+	}
+	p.cfg.GitAheadBehindCap = 10
+	if args := strings.Join(gitStatusArgs(p, false), " "); !strings.Contains(args, "--no-ahead-behind") {
+		t.Errorf("gitStatusArgs() with a cap = %q, want --no-ahead-behind", args)
+	}
+	segments := segmentGit(p)
+	if len(segments) == 0 || !strings.Contains(segments[0].Content, "10+"+p.symbols.RepoAhead) {
+		t.Errorf("segmentGit() = %+v, want 10+ commits ahead", segments)
+	}
+
+	info := parseGitBranchInfo([]string{"## main...origin/main [different]"})
+	if info["local"] != "main" || info["remote"] != "origin/main" || info["different"] == "" {
+		t.Errorf("parseGitBranchInfo() with --no-ahead-behind = %v", info)
+	}
+}
+
+func Test_gitAheadBehindDefaultCap(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	// a history deeper than the default cap, imported in one go
+	var history strings.Builder
+	for i := 0; i < 1500; i++ {
+		fmt.Fprintf(&history, "commit refs/heads/main\nmark :%d\ncommitter test <test@example.com> %d +0000\ndata 0\n", i+1, 1500000000+i)
+		if i > 0 {
+			fmt.Fprintf(&history, "from :%d\n", i)
+		}
+		history.WriteString("\n")
+	}
+	command := exec.Command("git", "fast-import", "--quiet", "--force")
+	command.Stdin = strings.NewReader(history.String())
+	if out, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("git fast-import: %v\n%s", err, out)
+	}
+	runGitCommand("git", "update-ref", "refs/remotes/origin/main", "main~1400")
+
+	// rev-list stops one commit past the cap instead of walking all 1400
+	ahead, _, err := gitAheadBehindLimited("HEAD", "origin/main", defaults.GitAheadBehindCap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ahead != defaults.GitAheadBehindCap+1 {
+		t.Errorf("gitAheadBehindLimited() = %d, want counting to stop at %d", ahead, defaults.GitAheadBehindCap+1)
+	}
+
+	cfg := defaults
+	cfg.GitMode = "compact"
+	p := testPowerline(cfg)
+	p.cwd = dir
+	segments := segmentGit(p)
+	if len(segments) == 0 || !strings.Contains(segments[0].Content, "1000+"+p.symbols.RepoAhead) {
+		t.Errorf("segmentGit() = %+v, want 1000+ commits ahead", segments)
+	}
+}
+
+func Test_gitHeadPushed(t *testing.T) {
+	newGitFixture(t, 2)
+	if pushed, ok := gitHeadPushed(); !pushed || !ok {
+		t.Errorf("gitHeadPushed() on origin/main = %v, %v, want true, true", pushed, ok)
+	}
+
+	runGitCommand("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "local")
+	if pushed, ok := gitHeadPushed(); pushed || !ok {
+		t.Errorf("gitHeadPushed() with a local commit = %v, %v, want false, true", pushed, ok)
+	}
+	// pushed to a remote other than the upstream still counts
+	runGitCommand("git", "update-ref", "refs/remotes/fork/main", "HEAD")
+	if pushed, ok := gitHeadPushed(); !pushed || !ok {
+		t.Errorf("gitHeadPushed() pushed to fork = %v, %v, want true, true", pushed, ok)
+	}
+
+	runGitCommand("git", "update-ref", "-d", "refs/remotes/fork/main")
+	runGitCommand("git", "update-ref", "-d", "refs/remotes/origin/main")
+	if _, ok := gitHeadPushed(); ok {
+		t.Errorf("gitHeadPushed() without remote-tracking branches reported a result")
+	}
+}
+
+func Test_gitConflictProminent(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		runGitCommand("git", args...)
+	}
+	write := func(content string) {
+		for _, name := range []string{"a.txt", "b.txt"} {
+			ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		}
+	}
+	write("base\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	git("checkout", "-q", "-b", "other")
+	write("other\n")
+	git("commit", "-q", "-am", "other")
+	git("checkout", "-q", "main")
+	write("main\n")
+	git("commit", "-q", "-am", "main")
+	git("merge", "-q", "other")
+	ioutil.WriteFile(filepath.Join(dir, "c.txt"), []byte("new\n"), 0644)
+
+	cfg := defaults
+	cfg.GitConflictProminent = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+	segments := segmentGit(p)
+	if len(segments) < 3 {
+		t.Fatalf("segmentGit() = %+v, want conflict and untracked stats", segments)
+	}
+	want := "2" + p.symbols.RepoConflicted
+	if segments[1].Content != want || segments[1].Background != p.theme.GitConflictProminentBg {
+		t.Errorf("first stats segment = %+v, want %q on the prominent background", segments[1], want)
+	}
+
+	stats := repoStats{ahead: 1, untracked: 1, conflicted: 2}
+	cfg.GitMode = "counts"
+	p = testPowerline(cfg)
+	if got, want := stats.GitSymbols(p), "2"+p.symbols.RepoConflicted+"1"+p.symbols.RepoAhead+"1"+p.symbols.RepoUntracked; got != want {
+		t.Errorf("GitSymbols() = %q, want %q", got, want)
+	}
+}
+
+func Test_gitRecurseSubmodules(t *testing.T) {
+	lib := newGitFixture(t, 1)
+	ioutil.WriteFile(filepath.Join(lib, "a.txt"), []byte("a\n"), 0644)
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "protocol.file.allow=always"}, args...)
+		if out, err := runGitCommand("git", args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "lib")
+
+	super := newGitFixture(t, 1)
+	git("submodule", "add", "-q", lib, "sub")
+	git("commit", "-q", "-m", "add sub")
+	ioutil.WriteFile(filepath.Join(super, "sub", "a.txt"), []byte("changed\n"), 0644)
+	ioutil.WriteFile(filepath.Join(super, "sub", "new.txt"), []byte("new\n"), 0644)
+
+	stats := gitSubmoduleStats(context.Background())
+	if stats.notStaged != 1 || stats.untracked != 1 {
+		t.Errorf("gitSubmoduleStats() = %+v, want 1 changed and 1 untracked file", stats)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if stats := gitSubmoduleStats(ctx); stats.dirty() {
+		t.Errorf("gitSubmoduleStats() after the timeout = %+v, want nothing", stats)
+	}
+
+	cfg := defaults
+	cfg.GitMode = "compact"
+	cfg.GitRecurseSubmodules = true
+	p := testPowerline(cfg)
+	p.cwd = super
+	segments := segmentGit(p)
+	if len(segments) == 0 || !strings.Contains(segments[0].Content, p.symbols.RepoRecursive) || segments[0].Background != p.theme.RepoDirtyBg {
+		t.Errorf("segmentGit() = %+v, want a dirty branch with the recursive indicator", segments)
+	}
+}
+
+func Test_gitGCNeeded(t *testing.T) {
+	dir := newGitFixture(t, 3)
+	if needed, err := gitGCNeeded(dir, 0); err != nil || needed {
+		t.Errorf("gitGCNeeded() without objects in objects/17 = %v, %v, want false", needed, err)
+	}
+
+	// git gc --auto with gc.auto=6700 wants more than 27 objects in objects/17
+	sample := filepath.Join(dir, ".git", "objects", "17")
+	os.MkdirAll(sample, 0755)
+	addObjects := func(n int) {
+		files, _ := ioutil.ReadDir(sample)
+		for i := len(files); i < len(files)+n; i++ {
+			ioutil.WriteFile(filepath.Join(sample, fmt.Sprintf("%038x", i)), nil, 0444)
+		}
+	}
+	addObjects(27)
+	ioutil.WriteFile(filepath.Join(sample, "tmp_obj_123"), nil, 0644)
+	if needed, err := gitGCNeeded(dir, 6700); err != nil || needed {
+		t.Errorf("gitGCNeeded() with 27 sampled objects = %v, %v, want false", needed, err)
+	}
+
+	cfg := defaults
+	cfg.GitShowGCHint = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+	hint := func() bool {
+		for _, segment := range segmentGit(p) {
+			if segment.Name == "git-gc" {
+				return true
+			}
+		}
+		return false
+	}
+	if hint() {
+		t.Errorf("segmentGit() with 27 sampled objects has a gc hint")
+	}
+	addObjects(1)
+	if needed, err := gitGCNeeded(dir, 6700); err != nil || !needed {
+		t.Errorf("gitGCNeeded() with 28 sampled objects = %v, %v, want true", needed, err)
+	}
+	if !hint() {
+		t.Errorf("segmentGit() with 28 sampled objects has no gc hint")
+	}
+}
+
+func Test_gitHideWhenClean(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	cfg := defaults
+	cfg.GitHideWhenClean = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	if segments := segmentGit(p); len(segments) != 0 {
+		t.Errorf("clean repository rendered %d segments, want none", len(segments))
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "new.txt"), []byte("x"), 0644)
+	if segments := segmentGit(p); len(segments) == 0 {
+		t.Errorf("dirty repository rendered no segments")
+	}
+}
+
+func Test_gitPushTarget(t *testing.T) {
+	newGitFixture(t, 5)
+	if target := getGitPushTarget("main"); target != "" {
+		t.Fatalf("getGitPushTarget() without push remote = %q, want empty", target)
+	}
+
+	runGitCommand("git", "update-ref", "refs/remotes/fork/main", "main~2")
+	runGitCommand("git", "config", "remote.pushDefault", "fork")
+	target := getGitPushTarget("main")
+	if target != "fork/main" {
+		t.Fatalf("getGitPushTarget() = %q, want fork/main", target)
+	}
+	ahead, behind, err := gitAheadBehind("main", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ahead != 2 || behind != 0 {
+		t.Errorf("gitAheadBehind() = %d, %d, want 2, 0", ahead, behind)
+	}
+
+	runGitCommand("git", "config", "branch.main.pushRemote", "origin")
+	if target := getGitPushTarget("main"); target != "origin/main" {
+		t.Errorf("getGitPushTarget() with branch pushRemote = %q, want origin/main", target)
+	}
+}
+
+func Test_gitStatusError(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	p := testPowerline(defaults)
+	p.cwd = dir
+
+	status := gitStatus
+	t.Cleanup(func() { gitStatus = status })
+
+	gitStatus = func(args ...string) (string, error) {
+		return "", errors.New("permission denied")
+	}
+	segments := segmentGit(p)
+	if len(segments) != 1 || !strings.HasSuffix(segments[0].Content, "main") {
+		t.Errorf("failed status rendered %+v, want only the main branch", segments)
+	}
+
+	gitStatus = func(args ...string) (string, error) {
+		return "## main\n?? new.txt\n", errors.New("permission denied")
+	}
+	segments = segmentGit(p)
+	if len(segments) != 2 || segments[1].Content != "1"+p.symbols.RepoUntracked {
+		t.Errorf("partial status rendered %+v, want the branch and one untracked file", segments)
+	}
+}
+
+func Test_gitBranchColorBySync(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	cfg := defaults
+	cfg.GitBranchColorBySync = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	status := gitStatus
+	t.Cleanup(func() { gitStatus = status })
+
+	tests := []struct {
+		name   string
+		status string
+		bg     uint8
+	}{
+		{"synced", "## main...origin/main\n", p.theme.GitSyncedBg},
+		{"ahead", "## main...origin/main [ahead 2]\n", p.theme.GitSyncAheadBg},
+		{"behind", "## main...origin/main [behind 1]\n", p.theme.GitSyncBehindBg},
+		{"diverged", "## main...origin/main [ahead 2, behind 1]\n", p.theme.GitSyncDivergedBg},
+		{"no upstream", "## main\n?? new.txt\n", p.theme.RepoDirtyBg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitStatus = func(args ...string) (string, error) {
+				return tt.status, nil
+			}
+			segments := segmentGit(p)
+			if len(segments) == 0 || segments[0].Background != tt.bg {
+				t.Errorf("segmentGit() = %+v, want the branch on %d", segments, tt.bg)
+			}
+		})
+	}
+}
+
+func Test_gitUntrackedNotDirty(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	ioutil.WriteFile(filepath.Join(dir, "scratch.txt"), []byte("x"), 0644)
+
+	for _, notDirty := range []bool{false, true} {
+		cfg := defaults
+		cfg.GitUntrackedNotDirty = notDirty
+		p := testPowerline(cfg)
+		p.cwd = dir
+
+		want := p.theme.RepoDirtyBg
+		if notDirty {
+			want = p.theme.RepoCleanBg
+		}
+		segments := segmentGit(p)
+		if len(segments) != 2 || segments[0].Background != want {
+			t.Errorf("segmentGit() with untracked not dirty %v = %+v, want the branch on %d", notDirty, segments, want)
+		}
+		if len(segments) == 2 && segments[1].Content != "1"+p.symbols.RepoUntracked {
+			t.Errorf("segmentGit() with untracked not dirty %v dropped the untracked count: %+v", notDirty, segments)
+		}
+	}
+}
+
+func Test_gitModeSync(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	cfg := defaults
+	cfg.GitMode = "sync"
+	cfg.GitShowIgnoredCount = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	status := gitStatus
+	t.Cleanup(func() { gitStatus = status })
+	gitStatus = func(args ...string) (string, error) {
+		return "## main...origin/main [ahead 2, behind 1]\nM  staged.txt\n M changed.txt\n?? new.txt\nUU conflict.txt\n!! ignored.txt\n", nil
+	}
+
+	var contents []string
+	for _, segment := range segmentGit(p)[1:] {
+		contents = append(contents, segment.Content)
+	}
+	want := []string{"2" + p.symbols.RepoAhead, "1" + p.symbols.RepoBehind}
+	if strings.Join(contents, " ") != strings.Join(want, " ") {
+		t.Errorf("segmentGit() in sync mode rendered %q, want only %q", contents, want)
+	}
+}
+
+func Test_gitShowNoUpstream(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	cfg := defaults
+	cfg.GitShowNoUpstream = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	status := gitStatus
+	t.Cleanup(func() { gitStatus = status })
+
+	tests := []struct {
+		status string
+		state  gitUpstreamState
+		local  bool
+	}{
+		{"## main", gitUpstreamNone, true},
+		{"## main...origin/main [gone]", gitUpstreamGone, false},
+		{"## main...origin/main", gitUpstreamTracking, false},
+	}
+	for _, tt := range tests {
+		if state := parseGitUpstreamState(parseGitBranchInfo([]string{tt.status})); state != tt.state {
+			t.Errorf("parseGitUpstreamState(%q) = %d, want %d", tt.status, state, tt.state)
+		}
+
+		gitStatus = func(args ...string) (string, error) {
+			return tt.status + "\n", nil
+		}
+		segments := segmentGit(p)
+		if len(segments) == 0 || !strings.Contains(segments[0].Content, "main") {
+			t.Fatalf("segmentGit() for %q = %+v, want the main branch", tt.status, segments)
+		}
+		if local := strings.HasSuffix(segments[0].Content, " "+p.symbols.RepoLocal); local != tt.local {
+			t.Errorf("segmentGit() for %q = %q, want local only marker %v", tt.status, segments[0].Content, tt.local)
+		}
+	}
+}
+
+func Test_gitStatsBeforeBranch(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	status := gitStatus
+	t.Cleanup(func() { gitStatus = status })
+	gitStatus = func(args ...string) (string, error) {
+		return "## main...origin/main [ahead 2]\n M a.txt\n", nil
+	}
+
+	for _, mode := range []string{"fancy", "compact", "simple", "counts"} {
+		cfg := defaults
+		cfg.GitMode = mode
+		cfg.GitStatsBeforeBranch = true
+		p := testPowerline(cfg)
+		p.cwd = dir
+
+		segments := segmentGit(p)
+		var contents []string
+		for _, segment := range segments {
+			contents = append(contents, segment.Content)
+		}
+		rendered := strings.Join(contents, "|")
+		ahead := strings.Index(rendered, p.symbols.RepoAhead)
+		changed := strings.Index(rendered, p.symbols.RepoNotStaged)
+		branch := strings.Index(rendered, "main")
+		if ahead < 0 || changed < 0 || branch < ahead || branch < changed {
+			t.Errorf("segmentGit() in %s mode = %q, want the stats before the branch", mode, rendered)
+		}
+		if mode == "fancy" && segments[len(segments)-1].Name != "git-branch" {
+			t.Errorf("segmentGit() in fancy mode ends with %+v, want the branch", segments[len(segments)-1])
+		}
+	}
+}
+
+func Test_gitSymbolOverrides(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	status := gitStatus
+	t.Cleanup(func() { gitStatus = status })
+	gitStatus = func(args ...string) (string, error) {
+		return "## main...origin/main [ahead 2]\n?? new.txt\n", nil
+	}
+
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.GitMode = "counts"
+	cfg.GitSymbolBranch = "BR"
+	cfg.GitSymbolAhead = "^"
+	cfg.GitSymbolUntracked = "?"
+	cfg.ModulesRight = nil
+	for _, module := range []string{"git", "gitlite"} {
+		cfg.Modules = []string{module}
+		p := newPowerline(cfg, dir, alignLeft)
+		if p.symbols.RepoBehind != cfg.Modes[cfg.Mode].RepoBehind {
+			t.Errorf("symbol without override = %q, want the %s one", p.symbols.RepoBehind, cfg.Mode)
+		}
+
+		want := "BR main"
+		if module == "git" {
+			want += " 2^1?"
+		}
+		if len(p.Segments[0]) != 1 || p.Segments[0][0].Content != want {
+			t.Errorf("%s segments = %+v, want %q", module, p.Segments[0], want)
+		}
+	}
+}
+
+func Test_gitStatsThinSeparator(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	ioutil.WriteFile(filepath.Join(dir, "new.txt"), []byte("x"), 0644)
+	cfg := defaults
+	cfg.GitStatsThinSeparator = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	segments := segmentGit(p)
+	if len(segments) != 2 {
+		t.Fatalf("segmentGit() rendered %d segments, want 2", len(segments))
+	}
+	if segments[0].Separator != p.symbols.SeparatorThin {
+		t.Errorf("branch separator = %q, want %q", segments[0].Separator, p.symbols.SeparatorThin)
+	}
+	if segments[1].Separator != "" {
+		t.Errorf("status separator = %q, want the default", segments[1].Separator)
+	}
+}
+
+func Test_getGitRepoType(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "protocol.file.allow=always"}, args...)
+		if out, err := runGitCommand("git", args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	check := func(cwd, want string) {
+		t.Helper()
+		os.Chdir(cwd)
+		if got := getGitRepoType(cwd); got != want {
+			t.Errorf("getGitRepoType(%q) = %q, want %q", cwd, got, want)
+		}
+	}
+
+	check(dir, "")
+
+	worktree := filepath.Join(t.TempDir(), "worktree")
+	git("worktree", "add", "-q", worktree)
+	check(worktree, "worktree")
+
+	os.Chdir(dir)
+	git("submodule", "add", "-q", dir, "child")
+	check(filepath.Join(dir, "child"), "submodule")
+
+	bare := filepath.Join(t.TempDir(), "bare.git")
+	git("init", "-q", "--bare", bare)
+	check(bare, "bare")
+
+	os.Chdir(dir)
+	git("config", "core.sparseCheckout", "true")
+	check(dir, "sparse")
+}
+
+func Test_gitStashFileCount(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	if _, err := gitStashFileCount(); err == nil {
+		t.Errorf("gitStashFileCount() without stash succeeded, want an error")
+	}
+
+	for _, name := range []string{"a.txt", "b c.txt", "d.txt"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0644)
+	}
+	runGitCommand("git", "add", ".")
+	runGitCommand("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "stash", "-q")
+	if count, err := gitStashFileCount(); err != nil || count != 3 {
+		t.Errorf("gitStashFileCount() = %d, %v, want 3", count, err)
+	}
+}
+
+func Test_classifyGitStashes(t *testing.T) {
+	tests := []struct {
+		subjects string
+		wip      int
+		named    int
+		ok       bool
+	}{
+		{"", 0, 0, true},
+		{"WIP on main: 207da1e init\n", 1, 0, true},
+		{"On main: old style\nOn main: hello\nWIP on main: 207da1e init\n", 1, 2, true},
+		{"On main: hello\nautostash\n", 0, 0, false},
+	}
+	for _, tt := range tests {
+		wip, named, ok := classifyGitStashes(tt.subjects)
+		if wip != tt.wip || named != tt.named || ok != tt.ok {
+			t.Errorf("classifyGitStashes(%q) = %d, %d, %v, want %d, %d, %v", tt.subjects, wip, named, ok, tt.wip, tt.named, tt.ok)
+		}
+	}
+
+	cfg := defaults
+	cfg.GitMode = "counts"
+	p := testPowerline(cfg)
+	stats := repoStats{stashed: 3, stashedWIP: 2, stashedNamed: 1}
+	want := "2" + p.symbols.RepoStashed + "1" + p.symbols.RepoStashNamed
+	if got := stats.GitSymbols(p); got != want {
+		t.Errorf("GitSymbols() with classified stashes = %q, want %q", got, want)
+	}
+}
+
+func Test_gitMergeBase(t *testing.T) {
+	newGitFixture(t, 3)
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		runGitCommand("git", args...)
+	}
+	want, _ := runGitCommand("git", "rev-parse", "--short=7", "HEAD")
+	want = strings.TrimSpace(want)
+
+	git("commit", "-q", "--allow-empty", "-m", "local")
+	if got, err := getGitMergeBase(""); err != nil || got != want {
+		t.Errorf("getGitMergeBase() = %q, %v, want %q", got, err, want)
+	}
+
+	git("checkout", "-q", "-b", "topic", "HEAD~2")
+	if _, err := getGitMergeBase(""); err == nil {
+		t.Errorf("getGitMergeBase() without an upstream succeeded")
+	}
+	base, _ := runGitCommand("git", "rev-parse", "--short=7", "HEAD")
+	git("commit", "-q", "--allow-empty", "-m", "topic")
+	if got, err := getGitMergeBase("main"); err != nil || got != strings.TrimSpace(base) {
+		t.Errorf("getGitMergeBase(main) = %q, %v, want %q", got, err, strings.TrimSpace(base))
+	}
+}
+
+func Test_gitNested(t *testing.T) {
+	outer := newGitFixture(t, 1)
+	inner := filepath.Join(outer, "inner")
+	os.Mkdir(inner, 0755)
+	runGitCommand("git", "-C", inner, "init", "-q")
+	runGitCommand("git", "-C", inner, "checkout", "-q", "-b", "feature")
+
+	root, branch, ok := enclosingGitRepo(inner)
+	wantRoot, _ := filepath.EvalSymlinks(outer)
+	if gotRoot, _ := filepath.EvalSymlinks(root); !ok || gotRoot != wantRoot || branch != "main" {
+		t.Errorf("enclosingGitRepo(inner) = %q, %q, %v, want %q, main, true", root, branch, ok, outer)
+	}
+	if root, branch, ok := enclosingGitRepo(outer); ok {
+		t.Errorf("enclosingGitRepo(outer) = %q, %q, want none", root, branch)
+	}
+}
+
+func Test_gitSymbolicHead(t *testing.T) {
+	tests := []struct {
+		ref      string
+		want     string
+		nonLocal bool
+	}{
+		{"refs/heads/main", "main", false},
+		{"refs/heads/feature/x", "feature/x", false},
+		{"refs/remotes/origin/main", "origin/main", true},
+		{"refs/tags/v1.0", "v1.0", true},
+		{"refs/namespaces/ci/refs/heads/main", "namespaces/ci/refs/heads/main", true},
+		{"main", "main", false},
+	}
+	for _, tt := range tests {
+		if got, nonLocal := shortSymbolicRef(tt.ref); got != tt.want || nonLocal != tt.nonLocal {
+			t.Errorf("shortSymbolicRef(%q) = %q, %v, want %q, %v", tt.ref, got, nonLocal, tt.want, tt.nonLocal)
+		}
+	}
+
+	dir := newGitFixture(t, 1)
+	runGitCommand("git", "symbolic-ref", "HEAD", "refs/remotes/origin/main")
+	cfg := defaults
+	cfg.GitShowNoUpstream = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+	want := p.symbols.RepoBranch + " origin/main " + p.symbols.RepoSymbolicRef
+	if segments := segmentGit(p); len(segments) == 0 || segments[0].Content != want {
+		t.Errorf("segmentGit() with HEAD on origin/main = %+v, want %q", segments, want)
+	}
+	if segments := segmentGitLite(p); len(segments) == 0 || segments[0].Content != want {
+		t.Errorf("segmentGitLite() with HEAD on origin/main = %+v, want %q", segments, want)
+	}
+}
+
+func Test_gitSkipOnFS(t *testing.T) {
+	tests := []struct {
+		fsType  string
+		skipped []string
+		want    bool
+	}{
+		{"nfs", []string{"nfs", "cifs"}, true},
+		{"cifs", []string{"nfs", " CIFS "}, true},
+		{"ext4", []string{"nfs", "cifs"}, false},
+		{"", []string{""}, false},
+		{"nfs", []string{}, false},
+	}
+	for _, tt := range tests {
+		if got := gitSkipsFilesystem(tt.fsType, tt.skipped); got != tt.want {
+			t.Errorf("gitSkipsFilesystem(%q, %q) = %v, want %v", tt.fsType, tt.skipped, got, tt.want)
+		}
+	}
+
+	dir := newGitFixture(t, 1)
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	gitDir, found := findGitDir(sub)
+	if !found || filepath.Dir(gitDir) != dir {
+		t.Fatalf("findGitDir() = %q, %v, want %q", gitDir, found, filepath.Join(dir, ".git"))
+	}
+	if branch, detached, err := readGitHead(gitDir); err != nil || branch != "main" || detached {
+		t.Errorf("readGitHead() = %q, %v, %v, want main", branch, detached, err)
+	}
+	runGitCommand("git", "checkout", "-q", "--detach")
+	hash, _ := runGitCommand("git", "rev-parse", "--short=7", "HEAD")
+	if branch, detached, err := readGitHead(gitDir); err != nil || branch != strings.TrimSpace(hash) || !detached {
+		t.Errorf("readGitHead() detached = %q, %v, %v, want %q", branch, detached, err, strings.TrimSpace(hash))
+	}
+}
+
+func Test_gitDetectLock(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	ioutil.WriteFile(filepath.Join(dir, "untracked.txt"), []byte("new\n"), 0644)
+
+	cfg := defaults
+	cfg.GitDetectLock = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+	if gitIndexLocked(dir) {
+		t.Errorf("gitIndexLocked() without a lock file = true")
+	}
+	if segments := segmentGit(p); len(segments) < 2 {
+		t.Errorf("segmentGit() without a lock file = %+v, want the untracked file", segments)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, ".git", "index.lock"), []byte{}, 0644)
+	if !gitIndexLocked(dir) {
+		t.Errorf("gitIndexLocked() with a lock file = false")
+	}
+	want := p.symbols.RepoBranch + " main " + p.symbols.RepoIndexLocked
+	if segments := segmentGit(p); len(segments) != 1 || segments[0].Content != want {
+		t.Errorf("segmentGit() with a lock file = %+v, want only %q", segments, want)
+	}
+}
+
+func Test_gitRepoConfig(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	runGitCommand("git", "config", "powerline.gitMode", "simple")
+	runGitCommand("git", "config", "powerline.disableStats", "untracked,stashed")
+	runGitCommand("git", "config", "remote.origin.gitMode", "compact")
+
+	values, err := gitRepoConfig(filepath.Join(dir, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := applyGitRepoConfig(defaults, values)
+	if cfg.GitMode != "simple" {
+		t.Errorf("GitMode = %q, want simple from the repository", cfg.GitMode)
+	}
+	if !reflect.DeepEqual(cfg.GitDisableStats, []string{"untracked", "stashed"}) {
+		t.Errorf("GitDisableStats = %v, want [untracked stashed]", cfg.GitDisableStats)
+	}
+
+	// values are decoded like git does, not cut at the first quote or comment
+	gitDir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(gitDir, "config"), []byte(
+		"[powerline]\n\tgitMode = \"simple\" # note\n\tprompt = \"a # b\" ; trailing\n\thideStats\n"), 0644)
+	values, err = gitRepoConfig(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"powerline.gitmode": "simple", "powerline.prompt": "a # b", "powerline.hidestats": "true"}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("gitRepoConfig() = %q, want %q", values, want)
+	}
+
+	cfg = defaults
+	cfg.GitShowIgnoredCount = true
+	cfg.GitShowSubmoduleCount = true
+	cfg.GitRecurseSubmodules = true
+	cfg.GitStashClassify = true
+	cfg.GitStashFileCount = true
+	cfg = applyGitRepoConfig(cfg, map[string]string{"powerline.hidestats": "true"})
+	if len(cfg.GitDisableStats) != 7 || cfg.GitShowIgnoredCount || cfg.GitShowSubmoduleCount ||
+		cfg.GitRecurseSubmodules || cfg.GitStashClassify || cfg.GitStashFileCount {
+		t.Errorf("applyGitRepoConfig() with hideStats = %+v, want all stats hidden", cfg)
+	}
+
+	cfg = defaults
+	cfg.Shell = "bare"
+	cfg.Modules = []string{"git"}
+	cfg.ModulesRight = []string{}
+	if p := newPowerline(cfg, dir, alignLeft); p.cfg.GitMode != "simple" {
+		t.Errorf("newPowerline() GitMode = %q, want simple from the repository", p.cfg.GitMode)
+	}
+	cfg.IgnoreRepos = []string{dir}
+	if p := newPowerline(cfg, dir, alignLeft); p.cfg.GitMode != defaults.GitMode {
+		t.Errorf("newPowerline() GitMode in an ignored repository = %q, want %q", p.cfg.GitMode, defaults.GitMode)
+	}
+}
+
+func Test_gitSubmodulePointers(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	git := func(dir string, args ...string) {
+		args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		runGitCommand("git", args...)
+	}
+	sub := filepath.Join(dir, "sub")
+	git(dir, "init", "-q", "sub")
+	git(sub, "commit", "-q", "--allow-empty", "-m", "0")
+	git(dir, "add", "sub")
+	git(dir, "commit", "-q", "-m", "add sub")
+	git(sub, "commit", "-q", "--allow-empty", "-m", "1")
+	// changes inside the submodule don't move its pointer
+	ioutil.WriteFile(filepath.Join(sub, "untracked.txt"), []byte("new\n"), 0644)
+
+	cfg := defaults
+	p := testPowerline(cfg)
+	status := func() repoStats {
+		out, _ := gitStatus(gitStatusArgs(p, false)...)
+		return parseGitStats(gitStatusLines(p, out))
+	}
+	if stats := status(); stats.dirty() {
+		t.Errorf("stats without -git-submodule-pointers = %+v, want clean", stats)
+	}
+
+	p.cfg.GitSubmodulePointers = true
+	if stats := status(); stats.notStaged != 1 || stats.staged != 0 || stats.untracked != 0 {
+		t.Errorf("stats with a moved submodule = %+v, want 1 not staged", stats)
+	}
+	git(dir, "add", "sub")
+	if stats := status(); stats.staged != 1 || stats.notStaged != 0 {
+		t.Errorf("stats with a staged submodule = %+v, want 1 staged", stats)
+	}
+}
+
+func Test_porcelainV2ToV1(t *testing.T) {
+	oid := "# branch.oid 0f256f599caad3c3416a4ba3c024cc8e879ffbf3"
+	tests := []struct {
+		status []string
+		want   []string
+	}{
+		{[]string{oid, "# branch.head main", "# branch.upstream origin/main", "# branch.ab +2 -1"},
+			[]string{"## main...origin/main [ahead 2, behind 1]"}},
+		{[]string{oid, "# branch.head main", "# branch.upstream origin/main", "# branch.ab +0 -0"},
+			[]string{"## main...origin/main"}},
+		{[]string{oid, "# branch.head main", "# branch.upstream origin/main", "# branch.ab +? -?"},
+			[]string{"## main...origin/main [different]"}},
+		{[]string{oid, "# branch.head main", "# branch.upstream origin/main"},
+			[]string{"## main...origin/main [gone]"}},
+		{[]string{oid, "# branch.head (detached)"}, []string{"## HEAD (no branch)"}},
+		{[]string{"# branch.oid (initial)", "# branch.head main"}, []string{"## No commits yet on main"}},
+		{[]string{oid, "# branch.head main",
+			"1 .M N... 100644 100644 100644 e69de29 e69de29 file.txt",
+			"1 .M S.MU 160000 160000 160000 da95b88 da95b88 dirty",
+			"1 .M SC.. 160000 160000 160000 da95b88 da95b88 moved",
+			"1 M. S... 160000 160000 160000 da95b88 a50a1d3 staged",
+			"u UU N... 100644 100644 100644 100644 e69de29 e69de29 e69de29 conflict.txt",
+			"? new.txt",
+			"! ignored.txt",
+		}, []string{"## main", " M file.txt", " M moved", "M  staged", "UU conflict.txt", "?? new.txt", "!! ignored.txt"}},
+	}
+	for _, tt := range tests {
+		if got := porcelainV2ToV1(tt.status); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("porcelainV2ToV1(%q) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func Test_gitCommitAge(t *testing.T) {
+	newGitFixture(t, 1)
+	runGitCommand("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "--date=2000-01-01T00:00:00Z", "-m", "rebased")
+
+	authored, err := getGitCommitTime("author")
+	if err != nil || authored.Year() != 2000 {
+		t.Errorf("getGitCommitTime(author) = %v, %v, want a time in 2000", authored, err)
+	}
+	committed, err := getGitCommitTime("commit")
+	if err != nil || time.Since(committed) > time.Hour {
+		t.Errorf("getGitCommitTime(commit) = %v, %v, want about now", committed, err)
+	}
+
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{30 * time.Second, "0m"},
+		{5 * time.Minute, "5m"},
+		{3 * time.Hour, "3h"},
+		{50 * time.Hour, "2d"},
+		{20 * 24 * time.Hour, "2w"},
+		{800 * 24 * time.Hour, "2y"},
+	}
+	for _, tt := range tests {
+		if got := formatGitAge(tt.age); got != tt.want {
+			t.Errorf("formatGitAge(%v) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
+func Test_parseGitTrailers(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"gerrit", "Fix it\n\nBody text.\n\nSigned-off-by: A <a@example.com>\nChange-Id: I0123456789abcdef0123456789abcdef01234567\n", "I0123456789abcdef0123456789abcdef01234567"},
+		{"subject only", "Change-Id: I0123456789abcdef\n", ""},
+		{"no trailers", "Fix it\n\nChange-Id: I0123456789abcdef is mentioned\nin the body\n", ""},
+		{"none", "Fix it\n\nBody text.\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGitTrailers(tt.message)["Change-Id"]; got != tt.want {
+				t.Errorf("parseGitTrailers()[Change-Id] = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_gerritChangeID(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Fix it\n\nChange-Id: I0123456789abcdef0123456789abcdef01234567\n", "I0123456"},
+		{"Fix it\n\nChange-Id: $(halt)\n", ""},
+		{"Fix it\n\nChange-Id: I0123`id`\n", ""},
+		{"Fix it\n", ""},
+	}
+	for _, tt := range tests {
+		if got := gerritChangeID(tt.message); got != tt.want {
+			t.Errorf("gerritChangeID(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func Test_formatGitBranchEvalSafe(t *testing.T) {
+	branch := "x`id`$(id)%n\"\\u"
+	tests := []struct {
+		shell    string
+		evalSafe bool
+		want     string
+	}{
+		{"bash", false, branch},
+		{"bash", true, "x\\`id\\`\\$(id)%n\\\"\\\\\\\\u"},
+		{"zsh", true, "x\\`id\\`\\$(id)%%n\\\"\\\\u"},
+	}
+	for _, tt := range tests {
+		cfg := defaults
+		cfg.Eval = true
+		cfg.EvalSafe = tt.evalSafe
+		p := testPowerline(cfg)
+		p.shell = cfg.Shells[tt.shell]
+		p.symbols.RepoBranch = ""
+		if got := formatGitBranch(p, branch); got != tt.want {
+			t.Errorf("formatGitBranch() for %s, eval-safe %v = %q, want %q", tt.shell, tt.evalSafe, got, tt.want)
+		}
+	}
+}
+
+func Test_gitBranchBasenameOnly(t *testing.T) {
+	dir := newGitFixture(t, 2)
+	cfg := defaults
+	cfg.GitBranchBasenameOnly = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	runGitCommand("git", "checkout", "-q", "-b", "feature/sub/thing")
+	if got, want := segmentGit(p)[0].Content, p.symbols.RepoBranch+" thing"; got != want {
+		t.Errorf("branch segment = %q, want %q", got, want)
+	}
+
+	runGitCommand("git", "checkout", "-q", "HEAD~1")
+	out, _ := runGitCommand("git", "rev-parse", "--short", "HEAD")
+	want := p.symbols.RepoBranch + " " + p.symbols.RepoDetached + " " + strings.TrimSpace(out)
+	if got := segmentGit(p)[0].Content; got != want {
+		t.Errorf("detached segment = %q, want %q", got, want)
+	}
+}
+
+func Test_formatRepoStatsCount(t *testing.T) {
+	tests := []struct {
+		nChanges int
+		limit    int
+		want     string
+	}{
+		{342, 0, "342"},
+		{98, 99, "98"},
+		{99, 99, "99"},
+		{100, 99, "99+"},
+		{342, 99, "99+"},
+	}
+	for _, tt := range tests {
+		if got := formatRepoStatsCount(tt.nChanges, tt.limit); got != tt.want {
+			t.Errorf("formatRepoStatsCount(%d, %d) = %q, want %q", tt.nChanges, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func Test_gitStatusArgsFSMonitor(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	for i := 0; i < 50; i++ {
+		ioutil.WriteFile(filepath.Join(dir, "file"+strconv.Itoa(i)+".txt"), []byte("x"), 0644)
+	}
+	runGitCommand("git", "add", ".")
+
+	cfg := defaults
+	cfg.GitAssumeUnchangedSize = 1
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	steps := []struct {
+		key, value string
+		want       bool
+	}{
+		{"", "", false},
+		{"core.fsmonitor", "true", false},
+		{"feature.manyFiles", "true", true},
+		{"core.untrackedCache", "false", false},
+		{"core.untrackedCache", "true", true},
+		{"core.fsmonitor", "false", false},
+		{"core.fsmonitor", ".git/hooks/query-watchman", true},
+	}
+	for _, step := range steps {
+		if step.key != "" {
+			runGitCommand("git", "config", step.key, step.value)
+		}
+		if got := gitFSMonitorEnabled(); got != step.want {
+			t.Errorf("gitFSMonitorEnabled() after %s=%s = %v, want %v", step.key, step.value, got, step.want)
+		}
+	}
+
+	if args := strings.Join(gitStatusArgs(p, false), " "); !strings.Contains(args, "-uno") {
+		t.Errorf("gitStatusArgs() without fsmonitor = %q, want untracked files skipped", args)
+	}
+	if args := strings.Join(gitStatusArgs(p, true), " "); strings.Contains(args, "-uno") {
+		t.Errorf("gitStatusArgs() with fsmonitor = %q, want untracked files scanned", args)
+	}
+}
+
+func Test_gitShowRemoteName(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	cfg := defaults
+	cfg.GitShowRemoteName = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	segments := segmentGit(p)
+	if len(segments) != 2 || segments[1].Content != p.symbols.RepoRemote+" origin" {
+		t.Errorf("segmentGit() = %+v, want the branch and the origin remote", segments)
+	}
+
+	runGitCommand("git", "checkout", "-q", "-b", "local")
+	if segments := segmentGit(p); len(segments) != 1 {
+		t.Errorf("segmentGit() without upstream = %+v, want only the branch", segments)
+	}
+}
+
+func Test_gitUnbornBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(wd) })
+	os.Chdir(dir)
+	runGitCommand("git", "init", "-q")
+	runGitCommand("git", "symbolic-ref", "HEAD", "refs/heads/main")
+
+	p := testPowerline(defaults)
+	p.cwd = dir
+	want := p.symbols.RepoBranch + " main " + p.symbols.RepoEmpty
+	for name, segment := range map[string]func(*powerline) []pwl.Segment{"git": segmentGit, "gitlite": segmentGitLite} {
+		segments := segment(p)
+		if len(segments) != 1 || segments[0].Content != want {
+			t.Errorf("%s segments = %+v, want %q", name, segments, want)
+		}
+	}
+}
+
+func Test_gitStatsSharedBg(t *testing.T) {
+	cfg := defaults
+	cfg.Shell = "bare"
+	cfg.Modules = []string{}
+	cfg.GitStatsSharedBg = 17
+	p := newPowerline(cfg, "/", alignLeft)
+
+	theme := defaults.Themes[defaults.Theme]
+	segments := repoStats{ahead: 1, staged: 2, untracked: 3}.GitSegments(p)
+	want := []uint8{theme.GitAheadFg, theme.GitStagedFg, theme.GitUntrackedFg}
+	if len(segments) != len(want) {
+		t.Fatalf("GitSegments() = %+v, want %d segments", segments, len(want))
+	}
+	for i, segment := range segments {
+		if segment.Background != 17 {
+			t.Errorf("segment %q background = %d, want 17", segment.Content, segment.Background)
+		}
+		if segment.Foreground != want[i] {
+			t.Errorf("segment %q foreground = %d, want %d", segment.Content, segment.Foreground, want[i])
+		}
+	}
+}
+
+func Test_gitDivergedGlyph(t *testing.T) {
+	cfg := defaults
+	cfg.GitDivergedGlyph = true
+	p := testPowerline(cfg)
+	s := p.symbols
+	tests := []struct {
+		name    string
+		stats   repoStats
+		fancy   []string
+		compact string
+	}{
+		{"ahead", repoStats{ahead: 2}, []string{"2" + s.RepoAhead}, " 2" + s.RepoAhead},
+		{"behind", repoStats{behind: 3}, []string{"3" + s.RepoBehind}, " 3" + s.RepoBehind},
+		{"diverged", repoStats{ahead: 2, behind: 3}, []string{s.RepoSyncDiverged + "2/3"}, " " + s.RepoSyncDiverged + "2/3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fancy []string
+			for _, segment := range tt.stats.GitSegments(p) {
+				fancy = append(fancy, segment.Content)
+			}
+			if strings.Join(fancy, ",") != strings.Join(tt.fancy, ",") {
+				t.Errorf("GitSegments() = %q, want %q", fancy, tt.fancy)
+			}
+			p.cfg.GitMode = "compact"
+			defer func() { p.cfg.GitMode = cfg.GitMode }()
+			if got := tt.stats.GitSymbols(p); got != tt.compact {
+				t.Errorf("GitSymbols() = %q, want %q", got, tt.compact)
+			}
+		})
+	}
+}
+
+func Test_gitInsideGitDir(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	p := testPowerline(defaults)
+	p.cwd = filepath.Join(dir, ".git", "hooks")
+	os.MkdirAll(p.cwd, 0755)
+	os.Chdir(p.cwd)
+
+	segments := segmentGit(p)
+	want := p.symbols.RepoInGitDir + " " + p.symbols.RepoBranch + " main"
+	if len(segments) != 1 || segments[0].Content != want {
+		t.Errorf("segmentGit() in .git/hooks = %+v, want %q", segments, want)
+	}
+}
+
+func Test_gitModeCounts(t *testing.T) {
+	cfg := defaults
+	cfg.GitMode = "counts"
+	p := testPowerline(cfg)
+	s := p.symbols
+
+	stats := repoStats{ahead: 2, behind: 3, untracked: 1}
+	want := "2" + s.RepoAhead + "3" + s.RepoBehind + "1" + s.RepoUntracked
+	if got := stats.GitSymbols(p); got != want {
+		t.Errorf("GitSymbols() = %q, want %q", got, want)
+	}
+
+	dir := newGitFixture(t, 1)
+	ioutil.WriteFile(filepath.Join(dir, "new.txt"), []byte("x"), 0644)
+	p.cwd = dir
+	want = s.RepoBranch + " main 1" + s.RepoUntracked
+	if segments := segmentGit(p); len(segments) != 1 || segments[0].Content != want {
+		t.Errorf("segmentGit() = %+v, want %q", segments, want)
+	}
+}
+
+func Test_gitDefaultBranch(t *testing.T) {
+	newGitFixture(t, 5)
+	if target := getGitDefaultBranch(); target != "" {
+		t.Fatalf("getGitDefaultBranch() without origin/HEAD = %q, want empty", target)
+	}
+
+	runGitCommand("git", "checkout", "-q", "-b", "feature", "HEAD~2")
+	runGitCommand("git", "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/main")
+	target := getGitDefaultBranch()
+	if target != "origin/main" {
+		t.Fatalf("getGitDefaultBranch() = %q, want origin/main", target)
+	}
+	if _, behind, err := gitAheadBehind("feature", target); err != nil || behind != 2 {
+		t.Errorf("gitAheadBehind() behind = %d, %v, want 2", behind, err)
+	}
+}
+
+func Test_gitPushDestination(t *testing.T) {
+	newGitFixture(t, 1)
+	runGitCommand("git", "checkout", "-q", "-b", "feature", "--track", "origin/main")
+
+	steps := []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{"", "", "origin/feature"},
+		{"push.default", "upstream", "origin/main"},
+		{"remote.pushDefault", "fork", "fork/feature"},
+		{"branch.feature.pushRemote", "origin", "origin/main"},
+	}
+	for _, step := range steps {
+		if step.key != "" {
+			runGitCommand("git", "config", step.key, step.value)
+		}
+		if got := getGitPushDestination("feature"); got != step.want {
+			t.Errorf("getGitPushDestination() after setting %s = %q, want %q", step.key, got, step.want)
+		}
+	}
+
+	runGitCommand("git", "checkout", "-q", "-b", "local")
+	if got := getGitPushDestination("local"); got != "fork/local" {
+		t.Errorf("getGitPushDestination() with only remote.pushDefault = %q, want fork/local", got)
+	}
+	runGitCommand("git", "config", "--unset", "remote.pushDefault")
+	if got := getGitPushDestination("local"); got != "" {
+		t.Errorf("getGitPushDestination() without remotes = %q, want empty", got)
+	}
+}
+
+func Test_gitCommitSubject(t *testing.T) {
+	dir := t.TempDir()
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	os.Chdir(dir)
+	runGitCommand("git", "init", "-q")
+	if got := getGitCommitSubject(30); got != "" {
+		t.Errorf("getGitCommitSubject() in an unborn repository = %q, want none", got)
+	}
+
+	runGitCommand("git", "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit", "-q", "--allow-empty", "-m", "Fix the frobnicator\n\nIt was broken.")
+	if got := getGitCommitSubject(30); got != "Fix the frobnicator" {
+		t.Errorf("getGitCommitSubject(30) = %q, want %q", got, "Fix the frobnicator")
+	}
+	if got := getGitCommitSubject(8); got != "Fix the"+ellipsis {
+		t.Errorf("getGitCommitSubject(8) = %q, want %q", got, "Fix the"+ellipsis)
+	}
+
+	// the subject is shown escaped whether or not -eval-safe is set
+	runGitCommand("git", "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit", "-q", "--allow-empty", "-m", "Fix $(touch pwned) `id` 100%")
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{"bash", "Fix \\$(touch pwned) \\`id\\` 100%"},
+		{"zsh", "Fix \\$(touch pwned) \\`id\\` 100%%"},
+	}
+	for _, tt := range tests {
+		cfg := defaults
+		cfg.Shell = tt.shell
+		cfg.GitShowCommitSubject = true
+		cfg.GitCommitSubjectMaxLength = 0
+		p := testPowerline(cfg)
+		p.shell = cfg.Shells[tt.shell]
+		p.cwd = dir
+		got := ""
+		for _, segment := range segmentGit(p) {
+			if segment.Name == "git-subject" {
+				got = segment.Content
+			}
+		}
+		if got != tt.want {
+			t.Errorf("git-subject in %s = %q, want %q", tt.shell, got, tt.want)
+		}
+	}
+}
+
+func Test_gitBisect(t *testing.T) {
+	dir := newGitFixture(t, 6)
+	gitDir := filepath.Join(dir, ".git")
+	if bisecting, _ := gitBisectState(gitDir); bisecting {
+		t.Errorf("gitBisectState() without a bisect reported one")
+	}
+
+	rev := func(name string) string {
+		out, _ := runGitCommand("git", "rev-parse", name)
+		return strings.TrimSpace(out)
+	}
+	writeLog := func(log string) {
+		ioutil.WriteFile(filepath.Join(gitDir, "BISECT_START"), []byte("main\n"), 0644)
+		ioutil.WriteFile(filepath.Join(gitDir, "BISECT_LOG"), []byte(log), 0644)
+	}
+
+	writeLog("git bisect start\n# status: waiting for both good and bad commits\n")
+	if bisecting, remaining := gitBisectState(gitDir); !bisecting || remaining != -1 {
+		t.Errorf("gitBisectState() before marking = %v, %d, want true, -1", bisecting, remaining)
+	}
+
+	log := fmt.Sprintf("git bisect start\n"+
+		"# bad: [%[1]s] 5\ngit bisect bad %[1]s\n"+
+		"# good: [%[2]s] 0\ngit bisect good %[2]s\n"+
+		"# good: [%[3]s] 2\ngit bisect good %[3]s\n"+
+		"# skip: [%[4]s] 4\ngit bisect skip %[4]s\n",
+		rev("HEAD"), rev("HEAD~5"), rev("HEAD~3"), rev("HEAD~1"))
+	bad, good := bisectRefs(log)
+	if bad != rev("HEAD") || !reflect.DeepEqual(good, []string{rev("HEAD~5"), rev("HEAD~3")}) {
+		t.Errorf("bisectRefs() = %q, %q", bad, good)
+	}
+	writeLog(log)
+	if bisecting, remaining := gitBisectState(gitDir); !bisecting || remaining != 3 {
+		t.Errorf("gitBisectState() = %v, %d, want true, 3", bisecting, remaining)
+	}
+}
+
+func Test_gitUpstreamFallback(t *testing.T) {
+	dir := newGitFixture(t, 2)
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		runGitCommand("git", args...)
+	}
+	cfg := defaults
+	cfg.GitUpstreamFallback = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	info := parseGitBranchInfo([]string{"## main...origin/main"})
+	if ref, state := resolveGitUpstream(p, "main", info); ref != "origin/main" || state != gitUpstreamTracking {
+		t.Errorf("resolveGitUpstream() with an upstream = %q, %d, want origin/main, tracking", ref, state)
+	}
+
+	git("config", "--unset", "branch.main.remote")
+	git("config", "--unset", "branch.main.merge")
+	git("commit", "-q", "--allow-empty", "-m", "local")
+	info = parseGitBranchInfo([]string{"## main"})
+	if ref, state := resolveGitUpstream(p, "main", info); ref != "origin/main" || state != gitUpstreamFallback {
+		t.Errorf("resolveGitUpstream() without an upstream = %q, %d, want origin/main, fallback", ref, state)
+	}
+	git("checkout", "-q", "-b", "topic")
+	if ref, state := resolveGitUpstream(p, "topic", info); ref != "" || state != gitUpstreamNone {
+		t.Errorf("resolveGitUpstream() without a remote branch = %q, %d, want none", ref, state)
+	}
+	git("config", "remote.fork.url", dir)
+	git("update-ref", "refs/remotes/fork/topic", "HEAD~1")
+	if ref, state := resolveGitUpstream(p, "topic", info); ref != "fork/topic" || state != gitUpstreamFallback {
+		t.Errorf("resolveGitUpstream() on another remote = %q, %d, want fork/topic, fallback", ref, state)
+	}
+
+	status := gitStatus
+	t.Cleanup(func() { gitStatus = status })
+	gitStatus = func(args ...string) (string, error) {
+		return "## topic\n", nil
+	}
+	if segments := segmentGit(p); len(segments) < 2 || segments[1].Content != "1"+p.symbols.RepoAhead {
+		t.Errorf("segmentGit() with a fallback upstream = %+v, want 1 ahead", segments)
+	}
+	p.cfg.GitUpstreamFallback = false
+	if segments := segmentGit(p); len(segments) != 1 {
+		t.Errorf("segmentGit() without -git-upstream-fallback = %+v, want the branch only", segments)
+	}
+}
+
+func Test_gitRemoteCount(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	cfg := defaults
+	cfg.GitShowRemoteCount = true
+	p := testPowerline(cfg)
+	p.cwd = dir
+
+	status := gitStatus
+	t.Cleanup(func() { gitStatus = status })
+	gitStatus = func(args ...string) (string, error) {
+		return "## main...origin/main\n", nil
+	}
+
+	if segments := segmentGit(p); len(segments) != 1 {
+		t.Errorf("segmentGit() with a single remote = %+v, want the branch only", segments)
+	}
+	runGitCommand("git", "config", "remote.upstream.url", dir)
+	if got := gitRemoteCount(); got != 2 {
+		t.Errorf("gitRemoteCount() = %d, want 2", got)
+	}
+	if segments := segmentGit(p); len(segments) != 2 || segments[1].Content != "2"+p.symbols.RepoRemotes {
+		t.Errorf("segmentGit() with two remotes = %+v, want 2%s", segments, p.symbols.RepoRemotes)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+// githubCachePath returns the file holding the number of pull requests waiting
+// for the user. powerline-go only reads it, some periodic job has to write it
+func githubCachePath(p *powerline) (string, error) {
+	if p.cfg.GithubCacheFile != "" {
+		return p.cfg.GithubCacheFile, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "powerline-go", "github-prs"), nil
+}
+
+// readGithubCache returns the count stored in the cache file, unless the file
+// is missing, malformed, or older than maxAge
+func readGithubCache(path string, maxAge time.Duration) (int, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	if maxAge > 0 && time.Since(info.ModTime()) > maxAge {
+		return 0, false
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, false
+	}
+	return count, true
+}
+
+// hasGithubRemote reports whether any remote in the output of `git remote -v`
+// points to GitHub
+func hasGithubRemote(remotes string) bool {
+	for _, line := range strings.Split(remotes, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && strings.Contains(fields[1], "github.com") {
+			return true
+		}
+	}
+	return false
+}
+
+func segmentGithub(p *powerline) []pwl.Segment {
+	path, err := githubCachePath(p)
+	if err != nil {
+		return []pwl.Segment{}
+	}
+	maxAge := time.Duration(p.cfg.GithubCacheMaxAge) * time.Second
+	count, ok := readGithubCache(path, maxAge)
+	if !ok || count <= 0 {
+		return []pwl.Segment{}
+	}
+	remotes, err := runGitCommand("git", "remote", "-v")
+	if err != nil || !hasGithubRemote(remotes) {
+		return []pwl.Segment{}
+	}
+
+	return []pwl.Segment{{
+		Name:       "github",
+		Content:    fmt.Sprintf("%s %d", p.symbols.GithubPullRequests, count),
+		Foreground: p.theme.GithubFg,
+		Background: p.theme.GithubBg,
+	}}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_readGithubCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "github-prs")
+
+	if _, ok := readGithubCache(path, time.Hour); ok {
+		t.Errorf("readGithubCache() without a cache file = ok, want not ok")
+	}
+
+	ioutil.WriteFile(path, []byte("3\n"), 0644)
+	if count, ok := readGithubCache(path, time.Hour); !ok || count != 3 {
+		t.Errorf("readGithubCache() = %d, %v, want 3, true", count, ok)
+	}
+
+	stale := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(path, stale, stale)
+	if _, ok := readGithubCache(path, time.Hour); ok {
+		t.Errorf("readGithubCache() with a stale cache file = ok, want not ok")
+	}
+	if count, ok := readGithubCache(path, 0); !ok || count != 3 {
+		t.Errorf("readGithubCache() without max age = %d, %v, want 3, true", count, ok)
+	}
+
+	ioutil.WriteFile(path, []byte("many"), 0644)
+	if _, ok := readGithubCache(path, time.Hour); ok {
+		t.Errorf("readGithubCache() with a malformed cache file = ok, want not ok")
+	}
+}
+
+func Test_hasGithubRemote(t *testing.T) {
+	tests := []struct {
+		remotes string
+		want    bool
+	}{
+		{"", false},
+		{"origin\tgit@github.com:justjanne/powerline-go.git (fetch)\norigin\tgit@github.com:justjanne/powerline-go.git (push)\n", true},
+		{"origin\thttps://gitlab.com/someone/project.git (fetch)\nupstream\thttps://github.com/justjanne/powerline-go (fetch)\n", true},
+		{"origin\thttps://gitlab.com/someone/project.git (fetch)\n", false},
+	}
+	for _, tt := range tests {
+		if got := hasGithubRemote(tt.remotes); got != tt.want {
+			t.Errorf("hasGithubRemote(%q) = %v, want %v", tt.remotes, got, tt.want)
+		}
+	}
+}
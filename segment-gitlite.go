@@ -1,9 +1,10 @@
 package main
 
 import (
-    "fmt"
-    "strings"
+	"fmt"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"strings"
 
 	pwl "github.com/justjanne/powerline-go/powerline"
 )
@@ -14,19 +15,79 @@ func getRepoRoot(repo *git.Repository) string {
 	return strings.TrimSpace(tree.Filesystem.Root())
 }
 
-func repoBranch(repo *git.Repository) string {
+// repoBranch returns the checked out branch or commit, whether the branch is
+// unborn because the repository has no commits yet, and whether HEAD points
+// to a ref other than a local branch
+func repoBranch(repo *git.Repository) (string, bool, bool) {
 	ref, err := repo.Head()
 	if err != nil {
-		return ""
+		head, err := repo.Reference(plumbing.HEAD, false)
+		if err != nil || head.Type() != plumbing.SymbolicReference {
+			return "", false, false
+		}
+		return head.Target().Short(), true, false
 	}
 	if ref.Name().IsBranch() {
-		return ref.Name().Short()
+		return ref.Name().Short(), false, false
+	} else if ref.Name() != plumbing.HEAD {
+		// a symbolic HEAD resolves to the ref it points to
+		name, symbolic := shortSymbolicRef(ref.Name().String())
+		return name, false, symbolic
 	} else {
-		return ref.Hash().String()[:7]
+		return ref.Hash().String()[:7], false, false
+	}
+}
+
+// gitLiteStatus returns the work tree status as read by go-git. It is a
+// variable so tests can simulate go-git failing to read the index.
+var gitLiteStatus = func(repo *git.Repository) (git.Status, error) {
+	tree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
 	}
+	return tree.Status()
+}
+
+// gitLiteStats counts the changes in the work tree, parsing git status if
+// go-git can't read the repository and -git-cli-fallback-on-error is set
+func gitLiteStats(p *powerline, repo *git.Repository) (repoStats, error) {
+	status, err := gitLiteStatus(repo)
+	if err == nil {
+		stats := repoStats{}
+		for _, file := range status {
+			switch {
+			case file.Staging == git.Untracked:
+				stats.untracked++
+			case file.Staging == git.UpdatedButUnmerged || file.Worktree == git.UpdatedButUnmerged:
+				stats.conflicted++
+			default:
+				if file.Staging != git.Unmodified {
+					stats.staged++
+				}
+				if file.Worktree != git.Unmodified {
+					stats.notStaged++
+				}
+			}
+		}
+		return stats, nil
+	}
+	if !p.cfg.GitCLIFallbackOnError {
+		return repoStats{}, err
+	}
+
+	p.debug(fmt.Sprintf("go-git status failed, falling back to git status: %s", err))
+	out, err := runGitCommand("git", "status", "--porcelain", "-b", "--ignore-submodules")
+	if err != nil {
+		return repoStats{}, err
+	}
+	return parseGitStats(strings.Split(out, "\n")), nil
 }
 
 func segmentGitLite(p *powerline) []pwl.Segment {
+	if hideGitInHome(p) {
+		return []pwl.Segment{}
+	}
+
 	repo, err := git.PlainOpenWithOptions(p.cwd, &git.PlainOpenOptions{
 		DetectDotGit:          true,
 		EnableDotGitCommonDir: true,
@@ -41,11 +102,25 @@ func segmentGitLite(p *powerline) []pwl.Segment {
 		}
 	}
 
-	branch := repoBranch(repo)
+	branch, unborn, symbolic := repoBranch(repo)
+	branch = escapeEvalSafe(p, gitBranchDisplayName(p, branch))
+	if unborn {
+		branch = fmt.Sprintf("%s %s", branch, p.symbols.RepoEmpty)
+	}
+	if symbolic {
+		branch = fmt.Sprintf("%s %s", branch, p.symbols.RepoSymbolicRef)
+	}
+
+	foreground, background := p.theme.RepoCleanFg, p.theme.RepoCleanBg
+	if p.cfg.GitLiteStatus {
+		if stats, err := gitLiteStats(p, repo); err == nil && stats.dirtyBranch(p) {
+			foreground, background = p.theme.RepoDirtyFg, p.theme.RepoDirtyBg
+		}
+	}
 	return []pwl.Segment{{
 		Name:       "git-branch",
-        Content: fmt.Sprintf("%s %s", p.symbols.RepoBranch, branch),
-		Foreground: p.theme.RepoCleanFg,
-		Background: p.theme.RepoCleanBg,
+		Content:    fmt.Sprintf("%s %s", p.symbols.RepoBranch, branch),
+		Foreground: foreground,
+		Background: background,
 	}}
 }
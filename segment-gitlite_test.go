@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func Test_gitLiteCLIFallback(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	ioutil.WriteFile("untracked.txt", []byte("new\n"), 0644)
+
+	status := gitLiteStatus
+	t.Cleanup(func() { gitLiteStatus = status })
+	gitLiteStatus = func(*git.Repository) (git.Status, error) {
+		return nil, errors.New("unknown index extension")
+	}
+
+	cfg := defaults
+	p := testPowerline(cfg)
+	p.cwd = dir
+	segments := segmentGitLite(p)
+	if len(segments) != 1 || segments[0].Background != p.theme.RepoCleanBg {
+		t.Fatalf("segmentGitLite() without status = %+v, want a clean segment", segments)
+	}
+
+	cfg.GitLiteStatus = true
+	p = testPowerline(cfg)
+	p.cwd = dir
+	segments = segmentGitLite(p)
+	if len(segments) != 1 || segments[0].Background != p.theme.RepoCleanBg {
+		t.Fatalf("segmentGitLite() without fallback = %+v, want a clean segment", segments)
+	}
+
+	cfg.GitCLIFallbackOnError = true
+	p = testPowerline(cfg)
+	p.cwd = dir
+	segments = segmentGitLite(p)
+	if len(segments) != 1 || segments[0].Background != p.theme.RepoDirtyBg {
+		t.Fatalf("segmentGitLite() with fallback = %+v, want a dirty segment", segments)
+	}
+}
@@ -18,6 +18,19 @@ func getMd5(text string) []byte {
 	return hasher.Sum(nil)
 }
 
+// hostnamePrompt returns the shell escape for the short hostname, or the hostname
+// itself for shells without one
+func hostnamePrompt(p *powerline) string {
+	switch p.cfg.Shell {
+	case "bash":
+		return "\\h"
+	case "zsh":
+		return "%m"
+	default:
+		return getHostName(p.hostname)
+	}
+}
+
 func segmentHost(p *powerline) []pwl.Segment {
 	var hostPrompt string
 	var foreground, background uint8
@@ -44,13 +57,7 @@ func segmentHost(p *powerline) []pwl.Segment {
 			foreground = p.theme.HostnameColorizedFgMap[background]
 		}
 	} else {
-		if p.cfg.Shell == "bash" {
-			hostPrompt = "\\h"
-		} else if p.cfg.Shell == "zsh" {
-			hostPrompt = "%m"
-		} else {
-			hostPrompt = getHostName(p.hostname)
-		}
+		hostPrompt = hostnamePrompt(p)
 
 		foreground = p.theme.HostnameFg
 		background = p.theme.HostnameBg
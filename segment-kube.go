@@ -130,6 +130,28 @@ func segmentKube(p *powerline) []pwl.Segment {
 			Foreground: p.theme.KubeNamespaceFg,
 			Background: p.theme.KubeNamespaceBg,
 		})
+		kubeIconHasBeenDrawnYet = true
+	}
+
+	// Helm namespaces can be switched independently of the kubectl context,
+	// which uses the default namespace if it doesn't set one
+	if helmNamespace := os.Getenv("HELM_NAMESPACE"); p.cfg.KubeShowHelm && helmNamespace != "" {
+		kubeNamespace := namespace
+		if kubeNamespace == "" {
+			kubeNamespace = "default"
+		}
+		if helmNamespace != kubeNamespace {
+			content := fmt.Sprintf("helm %s", helmNamespace)
+			if !kubeIconHasBeenDrawnYet {
+				content = fmt.Sprintf("⎈ %s", content)
+			}
+			segments = append(segments, pwl.Segment{
+				Name:       "kube-helm",
+				Content:    content,
+				Foreground: p.theme.KubeNamespaceFg,
+				Background: p.theme.KubeNamespaceBg,
+			})
+		}
 	}
 	return segments
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+	"github.com/mattn/go-runewidth"
+)
+
+// lastCommandName returns the command name of a command line, shortened to
+// maxLength columns
+func lastCommandName(commandLine string, maxLength int) string {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := fields[0]
+	if maxLength > 0 && runewidth.StringWidth(name) > maxLength {
+		name = runewidth.Truncate(name, maxLength, ellipsis)
+	}
+	return name
+}
+
+func segmentLastCommand(p *powerline) []pwl.Segment {
+	name := lastCommandName(p.cfg.LastCommand, p.cfg.LastCommandMaxLength)
+	if name == "" {
+		return []pwl.Segment{}
+	}
+
+	return []pwl.Segment{{
+		Name:       "lastcmd",
+		Content:    fmt.Sprintf("%s %s", p.symbols.LastCommand, escapeVariables(p, name)),
+		Foreground: p.theme.LastCommandFg,
+		Background: p.theme.LastCommandBg,
+	}}
+}
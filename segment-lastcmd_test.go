@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func Test_segmentLastCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"git status --short", "$ git"},
+		{"$(rm -rf ~) && ls", "$ \\$(rm"},
+		{"an-unusually-long-command-name --help", "$ an-unusually-long-c…"},
+	}
+	for _, tt := range tests {
+		cfg := defaults
+		cfg.Shell = "bash"
+		cfg.Mode = "ascii"
+		cfg.LastCommand = tt.command
+		p := testPowerline(cfg)
+		p.shell = cfg.Shells[cfg.Shell]
+
+		var got string
+		if segments := segmentLastCommand(p); len(segments) > 0 {
+			got = segments[0].Content
+		}
+		if got != tt.want {
+			t.Errorf("segmentLastCommand() for %q = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+const muxTimeout = 200 * time.Millisecond
+
+// parseTmuxState returns the states of a tmux pane from its window zoomed
+// flag, pane in mode flag and pane mode, separated by spaces
+func parseTmuxState(out string) []string {
+	fields := strings.Fields(out)
+	states := []string{}
+	if len(fields) > 0 && fields[0] == "1" {
+		states = append(states, "zoomed")
+	}
+	if len(fields) > 2 && fields[1] == "1" {
+		states = append(states, fields[2])
+	}
+	return states
+}
+
+func segmentMuxState(p *powerline) []pwl.Segment {
+	if os.Getenv("TMUX") == "" {
+		return []pwl.Segment{}
+	}
+
+	args := []string{"display-message", "-p"}
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		args = append(args, "-t", pane)
+	}
+	args = append(args, "#{window_zoomed_flag} #{pane_in_mode} #{pane_mode}")
+	ctx, cancel := context.WithTimeout(context.Background(), muxTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "tmux", args...).Output()
+	if err != nil {
+		return []pwl.Segment{}
+	}
+
+	symbols := map[string]string{
+		"zoomed":    p.symbols.MuxZoomed,
+		"copy-mode": p.symbols.MuxCopyMode,
+	}
+	shown := map[string]bool{}
+	for _, state := range p.cfg.MuxStates {
+		shown[strings.TrimSpace(state)] = true
+	}
+	var glyphs []string
+	for _, state := range parseTmuxState(string(out)) {
+		if symbol, ok := symbols[state]; ok && shown[state] {
+			glyphs = append(glyphs, symbol)
+		}
+	}
+	if len(glyphs) == 0 {
+		return []pwl.Segment{}
+	}
+
+	return []pwl.Segment{{
+		Name:       "mux-state",
+		Content:    strings.Join(glyphs, " "),
+		Foreground: p.theme.MuxStateFg,
+		Background: p.theme.MuxStateBg,
+	}}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_parseTmuxState(t *testing.T) {
+	tests := []struct {
+		out  string
+		want []string
+	}{
+		{"0 0 \n", []string{}},
+		{"1 0 \n", []string{"zoomed"}},
+		{"0 1 copy-mode\n", []string{"copy-mode"}},
+		{"1 1 copy-mode\n", []string{"zoomed", "copy-mode"}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := parseTmuxState(tt.out); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTmuxState(%q) = %v, want %v", tt.out, got, tt.want)
+		}
+	}
+}
+
+func Test_segmentMuxStateOutsideTmux(t *testing.T) {
+	value, found := os.LookupEnv("TMUX")
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("TMUX", value)
+		} else {
+			os.Unsetenv("TMUX")
+		}
+	})
+	os.Unsetenv("TMUX")
+
+	if segments := segmentMuxState(testPowerline(defaults)); len(segments) != 0 {
+		t.Errorf("segmentMuxState() outside tmux = %+v, want none", segments)
+	}
+}
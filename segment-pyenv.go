@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+const pyenvVersionFile = ".python-version"
+
+// pyenvVersion returns the Python version pyenv selects in dir, from
+// $PYENV_VERSION or the closest .python-version file, without running pyenv
+func pyenvVersion(dir string) string {
+	if version := os.Getenv("PYENV_VERSION"); version != "" {
+		return version
+	}
+	for {
+		content, err := ioutil.ReadFile(filepath.Join(dir, pyenvVersionFile))
+		if err == nil {
+			// several versions may be listed, the first one is used for python
+			if fields := strings.Fields(string(content)); len(fields) > 0 {
+				return fields[0]
+			}
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func segmentPyenv(p *powerline) []pwl.Segment {
+	if p.cfg.PythonPrefer != "pyenv" && virtualEnv() != "" {
+		return []pwl.Segment{}
+	}
+	version := pyenvVersion(p.cwd)
+	if version == "" {
+		return []pwl.Segment{}
+	}
+	return []pwl.Segment{{
+		Name:       "pyenv",
+		Content:    p.symbols.PyenvIndicator + " " + escapeVariables(p, version),
+		Foreground: p.theme.PyenvFg,
+		Background: p.theme.PyenvBg,
+	}}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_segmentPyenv(t *testing.T) {
+	for _, name := range []string{"PYENV_VERSION", "VIRTUAL_ENV", "CONDA_ENV_PATH", "CONDA_DEFAULT_ENV"} {
+		value, found := os.LookupEnv(name)
+		name := name
+		t.Cleanup(func() {
+			if found {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+		os.Unsetenv(name)
+	}
+
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "pkg")
+	os.MkdirAll(nested, 0755)
+	if version := pyenvVersion(nested); version != "" {
+		t.Errorf("pyenvVersion() without a version file = %q, want empty", version)
+	}
+
+	ioutil.WriteFile(filepath.Join(root, pyenvVersionFile), []byte("3.11.4\n2.7.18\n"), 0644)
+	if version := pyenvVersion(nested); version != "3.11.4" {
+		t.Errorf("pyenvVersion() = %q, want 3.11.4", version)
+	}
+	os.Setenv("PYENV_VERSION", "3.12.0")
+	if version := pyenvVersion(nested); version != "3.12.0" {
+		t.Errorf("pyenvVersion() with $PYENV_VERSION = %q, want 3.12.0", version)
+	}
+	os.Unsetenv("PYENV_VERSION")
+
+	os.Setenv("CONDA_DEFAULT_ENV", "science")
+	tests := []struct {
+		prefer string
+		venv   int
+		pyenv  int
+	}{
+		{"venv", 1, 0},
+		{"pyenv", 0, 1},
+	}
+	for _, tt := range tests {
+		cfg := defaults
+		cfg.Shell = "bare"
+		cfg.PythonPrefer = tt.prefer
+		p := testPowerline(cfg)
+		p.shell = cfg.Shells[cfg.Shell]
+		p.cwd = nested
+
+		if got := segmentVirtualEnv(p); len(got) != tt.venv {
+			t.Errorf("segmentVirtualEnv() preferring %s = %+v, want %d segments", tt.prefer, got, tt.venv)
+		}
+		if got := segmentPyenv(p); len(got) != tt.pyenv {
+			t.Errorf("segmentPyenv() preferring %s = %+v, want %d segments", tt.prefer, got, tt.pyenv)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+const sshAgentTimeout = 500 * time.Millisecond
+
+// parseSSHAddList interprets the output of ssh-add -l, which exits with 1 if
+// the agent has no identities and with 2 if it can't be reached
+func parseSSHAddList(out string, err error) (int, bool) {
+	if err == nil {
+		return strings.Count(strings.TrimSpace(out), "\n") + 1, true
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return 0, true
+	}
+	return 0, false
+}
+
+// sshAgentKeys returns the number of identities loaded into the agent at
+// $SSH_AUTH_SOCK, and false if there is no agent
+func sshAgentKeys() (int, bool) {
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sshAgentTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "ssh-add", "-l").Output()
+	return parseSSHAddList(string(out), err)
+}
+
+func segmentSSHAgent(p *powerline) []pwl.Segment {
+	keys, ok := sshAgentKeys()
+	if !ok {
+		return []pwl.Segment{}
+	}
+
+	foreground, background := p.theme.SSHAgentFg, p.theme.SSHAgentBg
+	if keys == 0 {
+		foreground, background = p.theme.SSHAgentEmptyFg, p.theme.SSHAgentEmptyBg
+	}
+	return []pwl.Segment{{
+		Name:       "sshagent",
+		Content:    fmt.Sprintf("%s %d", p.symbols.SSHAgent, keys),
+		Foreground: foreground,
+		Background: background,
+	}}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"text/template"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+	"github.com/mattn/go-runewidth"
+)
+
+// templateMaxLength caps the width of the rendered template segment
+const templateMaxLength = 128
+
+// TemplateContext is the data passed to the -template-segment template. Its
+// fields and methods are a stable interface, do not rename or remove them.
+type TemplateContext struct {
+	// Cwd is the current working directory
+	Cwd string
+	// Hostname is the short hostname of the machine
+	Hostname string
+	// Username is the name of the current user
+	Username string
+	// ExitCode is the exit code of the previous command
+	ExitCode int
+	// Time is the time the prompt is rendered at
+	Time time.Time
+
+	p *powerline
+}
+
+// Branch returns the current git branch, or an empty string outside of a repository
+func (c TemplateContext) Branch() string {
+	return c.p.gitBranch()
+}
+
+// Env returns the value of the environment variable name
+func (c TemplateContext) Env(name string) string {
+	return os.Getenv(name)
+}
+
+// limitedBuffer stops accepting writes once its limit has been reached
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if remaining := b.limit - b.Len(); len(data) > remaining {
+		if remaining > 0 {
+			b.Buffer.Write(data[:remaining])
+		}
+		return len(data), nil
+	}
+	return b.Buffer.Write(data)
+}
+
+func segmentTemplate(p *powerline) []pwl.Segment {
+	if p.cfg.TemplateSegment == "" {
+		return []pwl.Segment{}
+	}
+
+	// no custom functions are registered, templates can only read the context
+	tmpl, err := template.New("segment").Option("missingkey=zero").Parse(p.cfg.TemplateSegment)
+	if err != nil {
+		warn("Invalid template segment: " + err.Error())
+		return []pwl.Segment{}
+	}
+
+	ctx := TemplateContext{
+		Cwd:      p.cwd,
+		Hostname: getHostName(p.hostname),
+		Username: p.username,
+		ExitCode: p.cfg.PrevError,
+		Time:     time.Now(),
+		p:        p,
+	}
+	out := &limitedBuffer{limit: templateMaxLength * 4}
+	err = tmpl.Execute(out, ctx)
+	if err != nil {
+		warn("Failed to render template segment: " + err.Error())
+		return []pwl.Segment{}
+	}
+
+	content := runewidth.Truncate(out.String(), templateMaxLength, ellipsis)
+	if content == "" {
+		return []pwl.Segment{}
+	}
+	return []pwl.Segment{{
+		Name:    "template",
+		Content: escapeVariables(p, content),
+	}}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func templatePowerline(shell string, template string) *powerline {
+	cfg := defaults
+	cfg.Shell = shell
+	cfg.TemplateSegment = template
+	cfg.PrevError = 2
+	p := &powerline{
+		cfg:      cfg,
+		cwd:      "/src/project",
+		hostname: "host.example.com",
+		username: "user",
+		shell:    cfg.Shells[shell],
+	}
+	p.branchOnce.Do(func() { p.branch = "main" })
+	return p
+}
+
+func Test_segmentTemplateContext(t *testing.T) {
+	value, found := os.LookupEnv("POWERLINE_TEMPLATE_TEST")
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("POWERLINE_TEMPLATE_TEST", value)
+		} else {
+			os.Unsetenv("POWERLINE_TEMPLATE_TEST")
+		}
+	})
+	os.Setenv("POWERLINE_TEMPLATE_TEST", "env")
+
+	p := templatePowerline("bare",
+		`{{.Username}}@{{.Hostname}}:{{.Cwd}} {{.ExitCode}} {{.Branch}} {{.Env "POWERLINE_TEMPLATE_TEST"}} {{if not .Time.IsZero}}now{{end}}`)
+	segments := segmentTemplate(p)
+	want := "user@host:/src/project 2 main env now"
+	if len(segments) != 1 || segments[0].Content != want {
+		t.Errorf("segmentTemplate() = %+v, want %q", segments, want)
+	}
+}
+
+func Test_segmentTemplateMissing(t *testing.T) {
+	os.Unsetenv("POWERLINE_TEMPLATE_UNSET")
+	tests := []struct {
+		template string
+		want     []string
+	}{
+		// missing values render as their zero value instead of <no value>
+		{`[{{.Env "POWERLINE_TEMPLATE_UNSET"}}]`, []string{"[]"}},
+		// and rendering nothing at all leaves out the segment
+		{`{{.Env "POWERLINE_TEMPLATE_UNSET"}}`, nil},
+		// fields that don't exist fail the template instead of rendering
+		{`{{.Nope}}`, nil},
+		// so do invalid templates
+		{`{{.Cwd`, nil},
+	}
+	for _, tt := range tests {
+		segments := segmentTemplate(templatePowerline("bare", tt.template))
+		var contents []string
+		for _, segment := range segments {
+			contents = append(contents, segment.Content)
+		}
+		if strings.Join(contents, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("segmentTemplate(%q) = %q, want %q", tt.template, contents, tt.want)
+		}
+	}
+}
+
+func Test_segmentTemplateLength(t *testing.T) {
+	p := templatePowerline("bare", "{{.Cwd}}")
+	p.cwd = strings.Repeat("a", templateMaxLength*10)
+	segments := segmentTemplate(p)
+	if len(segments) != 1 {
+		t.Fatalf("segmentTemplate() = %+v, want one segment", segments)
+	}
+	content := segments[0].Content
+	if width := len([]rune(content)); width != templateMaxLength {
+		t.Errorf("segmentTemplate() width = %d, want it truncated to %d", width, templateMaxLength)
+	}
+	if !strings.HasSuffix(content, ellipsis) {
+		t.Errorf("segmentTemplate() = %q, want it to end with an ellipsis", content)
+	}
+
+	out := &limitedBuffer{limit: 4}
+	if n, err := out.Write([]byte("abcdef")); n != 6 || err != nil {
+		t.Errorf("limitedBuffer.Write() = %d, %v, want all bytes accepted", n, err)
+	}
+	out.Write([]byte("gh"))
+	if out.String() != "abcd" {
+		t.Errorf("limitedBuffer = %q, want output stopped at the limit", out.String())
+	}
+}
+
+func Test_segmentTemplateEscaping(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{"bare", "$(id) `id`"},
+		{"bash", "\\$(id) \\`id\\`"},
+		{"zsh", "\\$(id) \\`id\\`"},
+	}
+	for _, tt := range tests {
+		p := templatePowerline(tt.shell, "{{.Env \"POWERLINE_TEMPLATE_ESCAPE\"}}")
+		os.Setenv("POWERLINE_TEMPLATE_ESCAPE", "$(id) `id`")
+		segments := segmentTemplate(p)
+		os.Unsetenv("POWERLINE_TEMPLATE_ESCAPE")
+		if len(segments) != 1 || segments[0].Content != tt.want {
+			t.Errorf("segmentTemplate() in %s = %+v, want %q", tt.shell, segments, tt.want)
+		}
+	}
+}
@@ -11,6 +11,22 @@ import (
 	pwl "github.com/justjanne/powerline-go/powerline"
 )
 
+// expandTitleFormat fills in the placeholders of -title-format
+func expandTitleFormat(p *powerline) string {
+	return expandPlaceholders(p.cfg.TitleFormat, map[string]func() string{
+		"cwd": func() string {
+			return p.cwd
+		},
+		"branch": p.gitBranch,
+		"host": func() string {
+			return p.hostname
+		},
+		"user": func() string {
+			return p.username
+		},
+	})
+}
+
 func segmentTermTitle(p *powerline) []pwl.Segment {
 	var title string
 
@@ -19,7 +35,9 @@ func segmentTermTitle(p *powerline) []pwl.Segment {
 		return []pwl.Segment{}
 	}
 
-	if p.cfg.Shell == "bash" {
+	if p.cfg.TitleFormat != "" && p.shell.TitleTemplate != "" {
+		title = fmt.Sprintf(p.shell.TitleTemplate, escapePromptText(p, expandTitleFormat(p)))
+	} else if p.cfg.Shell == "bash" {
 		title = "\\[\\e]0;\\u@\\h: \\w\\a\\]"
 	} else if p.cfg.Shell == "zsh" {
 		title = "%{\033]0;%n@%m: %~\007%}"
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_segmentTermTitle(t *testing.T) {
+	term, found := os.LookupEnv("TERM")
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("TERM", term)
+		} else {
+			os.Unsetenv("TERM")
+		}
+	})
+	os.Setenv("TERM", "xterm-256color")
+
+	tests := []struct {
+		shell  string
+		format string
+		want   string
+	}{
+		{"bash", "", "\\[\\e]0;\\u@\\h: \\w\\a\\]"},
+		{"bare", "{user}@{host}: {cwd} ({branch})", "\x1b]0;user@host: /src/$x 100% (main)\x1b\\"},
+		{"bash", "{user}@{host}: {cwd} ({branch})", `\[\e]0;user@host: /src/\$x 100% (main)\e\\\\\]`},
+		{"zsh", "{user}@{host}: {cwd} ({branch})", "%{\x1b]0;user@host: /src/\\$x 100%% (main)\x1b\\\\%}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			cfg := defaults
+			cfg.Shell = tt.shell
+			cfg.TitleFormat = tt.format
+			p := &powerline{
+				cfg:      cfg,
+				cwd:      "/src/$x 100%",
+				hostname: "host",
+				username: "user",
+				shell:    cfg.Shells[tt.shell],
+			}
+			p.branchOnce.Do(func() { p.branch = "main" })
+			segments := segmentTermTitle(p)
+			if len(segments) != 1 || segments[0].Content != tt.want {
+				t.Errorf("segmentTermTitle() = %+v, want %q", segments, tt.want)
+			}
+		})
+	}
+}
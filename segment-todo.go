@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+const todoTimeout = time.Second
+
+// countTodos sums up the TODO and FIXME markers git grep finds in tracked files
+func countTodos() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), todoTimeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, "git", "grep", "-I", "-c", "-E", `TODO|FIXME`)
+	command.Env = gitProcessEnv
+	out, err := command.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		// git grep exits with 1 if nothing matched
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if idx := strings.LastIndexByte(line, ':'); idx >= 0 {
+			n, _ := strconv.Atoi(line[idx+1:])
+			count += n
+		}
+	}
+	return count, nil
+}
+
+// cachedTodoCount returns the number of TODO markers in the current
+// repository, reusing the count from cacheFile as long as HEAD is unchanged
+func cachedTodoCount(cacheFile, head string) (int, error) {
+	if cached, err := ioutil.ReadFile(cacheFile); err == nil {
+		fields := strings.Fields(string(cached))
+		if len(fields) == 2 && fields[0] == head {
+			return strconv.Atoi(fields[1])
+		}
+	}
+	count, err := countTodos()
+	if err != nil {
+		return 0, err
+	}
+	os.MkdirAll(filepath.Dir(cacheFile), 0700)
+	ioutil.WriteFile(cacheFile, []byte(fmt.Sprintf("%s %d\n", head, count)), 0600)
+	return count, nil
+}
+
+func segmentTodo(p *powerline) []pwl.Segment {
+	root, err := repoRoot(p.cwd)
+	if err != nil {
+		return []pwl.Segment{}
+	}
+	head, err := runGitCommand("git", "rev-parse", "HEAD")
+	if err != nil {
+		return []pwl.Segment{}
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	cacheFile := filepath.Join(cacheDir, "powerline-go", fmt.Sprintf("todo-%x", getMd5(root)))
+
+	count, err := cachedTodoCount(cacheFile, strings.TrimSpace(head))
+	if err != nil {
+		p.debug("Counting TODO markers failed: " + err.Error())
+		return []pwl.Segment{}
+	}
+	if count == 0 {
+		return []pwl.Segment{}
+	}
+	return []pwl.Segment{{
+		Name:       "todo",
+		Content:    fmt.Sprintf("%s %d", p.symbols.Todo, count),
+		Foreground: p.theme.TodoFg,
+		Background: p.theme.TodoBg,
+	}}
+}
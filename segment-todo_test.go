@@ -0,0 +1,28 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func Test_cachedTodoCount(t *testing.T) {
+	dir := newGitFixture(t, 1)
+	ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("// TODO: one\n// FIXME: two\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.go"), []byte("// TODO: three\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "untracked.go"), []byte("// TODO: ignored\n"), 0644)
+	runGitCommand("git", "add", "a.go", "b.go")
+
+	cacheFile := filepath.Join(t.TempDir(), "todo")
+	if count, err := cachedTodoCount(cacheFile, "1"); err != nil || count != 3 {
+		t.Errorf("cachedTodoCount() = %d, %v, want 3", count, err)
+	}
+
+	runGitCommand("git", "rm", "-q", "-f", "b.go")
+	if count, _ := cachedTodoCount(cacheFile, "1"); count != 3 {
+		t.Errorf("cachedTodoCount() with unchanged HEAD = %d, want the cached 3", count)
+	}
+	if count, _ := cachedTodoCount(cacheFile, "2"); count != 2 {
+		t.Errorf("cachedTodoCount() with new HEAD = %d, want 2", count)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+func segmentUserhost(p *powerline) []pwl.Segment {
+	format := p.cfg.UserhostFormat
+	// $DEFAULT_USER only counts with -hide-default-user, like for the user segment
+	if (p.cfg.DefaultUser != "" || p.cfg.HideDefaultUser) && isDefaultUser(p) {
+		// the separators around {user} make no sense on their own
+		if !strings.Contains(format, "{host}") {
+			return []pwl.Segment{}
+		}
+		format = "{host}"
+	}
+
+	content := strings.NewReplacer(
+		"{user}", usernamePrompt(p),
+		"{host}", hostnamePrompt(p),
+	).Replace(format)
+	if content == "" {
+		return []pwl.Segment{}
+	}
+
+	background := p.theme.UsernameBg
+	if p.userIsAdmin {
+		background = p.theme.UsernameRootBg
+	}
+	return []pwl.Segment{{
+		Name:       "userhost",
+		Content:    content,
+		Foreground: p.theme.UsernameFg,
+		Background: background,
+	}}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_segmentUserhost(t *testing.T) {
+	unsetDefaultUser(t)
+
+	tests := []struct {
+		format      string
+		defaultUser string
+		envUser     string
+		hide        bool
+		want        string
+	}{
+		{"{user}@{host}", "", "", false, "alice@box"},
+		{"{user} on {host}", "", "", false, "alice on box"},
+		{"{user}", "", "", false, "alice"},
+		{"{user}@{host}", "bob", "", false, "alice@box"},
+		{"{user}@{host}", "alice", "", false, "box"},
+		{"{user}", "alice", "", false, ""},
+		// $DEFAULT_USER is only used with -hide-default-user
+		{"{user}@{host}", "", "alice", false, "alice@box"},
+		{"{user}@{host}", "", "alice", true, "box"},
+	}
+	for _, tt := range tests {
+		os.Unsetenv("DEFAULT_USER")
+		if tt.envUser != "" {
+			os.Setenv("DEFAULT_USER", tt.envUser)
+		}
+		cfg := defaults
+		cfg.Shell = "bare"
+		cfg.UserhostFormat = tt.format
+		cfg.DefaultUser = tt.defaultUser
+		cfg.HideDefaultUser = tt.hide
+		p := testPowerline(cfg)
+		p.username = "alice"
+		p.hostname = "box.example.com"
+
+		segments := segmentUserhost(p)
+		got := ""
+		if len(segments) > 0 {
+			got = segments[0].Content
+		}
+		if got != tt.want {
+			t.Errorf("segmentUserhost() with %q and default user %q = %q, want %q", tt.format, tt.defaultUser, got, tt.want)
+		}
+	}
+}
@@ -1,18 +1,37 @@
 package main
 
 import (
+	"os"
+
 	pwl "github.com/justjanne/powerline-go/powerline"
 )
 
-func segmentUser(p *powerline) []pwl.Segment {
-	var userPrompt string
+// usernamePrompt returns the shell escape for the username, or the username itself
+// for shells without one
+func usernamePrompt(p *powerline) string {
 	switch p.cfg.Shell {
 	case "bash":
-		userPrompt = "\\u"
+		return "\\u"
 	case "zsh":
-		userPrompt = "%n"
+		return "%n"
 	default:
-		userPrompt = p.username
+		return p.username
+	}
+}
+
+// isDefaultUser reports whether the current user is the configured default
+// user, falling back to $DEFAULT_USER
+func isDefaultUser(p *powerline) bool {
+	defaultUser := p.cfg.DefaultUser
+	if defaultUser == "" {
+		defaultUser = os.Getenv("DEFAULT_USER")
+	}
+	return defaultUser != "" && p.username == defaultUser
+}
+
+func segmentUser(p *powerline) []pwl.Segment {
+	if p.cfg.HideDefaultUser && isDefaultUser(p) {
+		return []pwl.Segment{}
 	}
 
 	var background uint8
@@ -24,7 +43,7 @@ func segmentUser(p *powerline) []pwl.Segment {
 
 	return []pwl.Segment{{
 		Name:       "user",
-		Content:    userPrompt,
+		Content:    usernamePrompt(p),
 		Foreground: p.theme.UsernameFg,
 		Background: background,
 	}}
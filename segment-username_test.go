@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// unsetDefaultUser clears $DEFAULT_USER for the duration of the test
+func unsetDefaultUser(t *testing.T) {
+	value, found := os.LookupEnv("DEFAULT_USER")
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("DEFAULT_USER", value)
+		} else {
+			os.Unsetenv("DEFAULT_USER")
+		}
+	})
+	os.Unsetenv("DEFAULT_USER")
+}
+
+func Test_segmentUserHideDefault(t *testing.T) {
+	unsetDefaultUser(t)
+
+	tests := []struct {
+		name        string
+		hide        bool
+		defaultUser string
+		envUser     string
+		want        int
+	}{
+		{"not hidden", false, "alice", "", 1},
+		{"matching flag", true, "alice", "", 0},
+		{"other user", true, "bob", "", 1},
+		{"matching env", true, "", "alice", 0},
+		{"flag before env", true, "bob", "alice", 1},
+		{"no default user", true, "", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("DEFAULT_USER", tt.envUser)
+			cfg := defaults
+			cfg.Shell = "bare"
+			cfg.HideDefaultUser = tt.hide
+			cfg.DefaultUser = tt.defaultUser
+			p := testPowerline(cfg)
+			p.username = "alice"
+
+			if got := segmentUser(p); len(got) != tt.want {
+				t.Errorf("segmentUser() = %+v, want %d segments", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	pwl "github.com/justjanne/powerline-go/powerline"
+)
+
+const vaultTimeout = 500 * time.Millisecond
+
+// vaultTokenValid asks the Vault server at addr whether token is still valid
+func vaultTokenValid(addr, token string) bool {
+	request, err := http.NewRequest("GET", strings.TrimSuffix(addr, "/")+"/v1/auth/token/lookup-self", nil)
+	if err != nil {
+		return false
+	}
+	request.Header.Set("X-Vault-Token", token)
+	client := http.Client{Timeout: vaultTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+	return response.StatusCode == http.StatusOK
+}
+
+func segmentVault(p *powerline) []pwl.Segment {
+	addr := os.Getenv("VAULT_ADDR")
+	token := os.Getenv("VAULT_TOKEN")
+	if addr == "" && token == "" {
+		return []pwl.Segment{}
+	}
+
+	host := "vault"
+	if u, err := url.Parse(addr); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+
+	foreground, background := p.theme.VaultFg, p.theme.VaultBg
+	if p.cfg.VaultCheckToken && addr != "" && !vaultTokenValid(addr, token) {
+		foreground, background = p.theme.VaultInvalidFg, p.theme.VaultInvalidBg
+	}
+	return []pwl.Segment{{
+		Name:       "vault",
+		Content:    p.symbols.Lock + " " + host,
+		Foreground: foreground,
+		Background: background,
+	}}
+}
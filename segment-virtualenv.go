@@ -9,7 +9,9 @@ import (
 	pwl "github.com/justjanne/powerline-go/powerline"
 )
 
-func segmentVirtualEnv(p *powerline) []pwl.Segment {
+// virtualEnv returns the active virtualenv or conda environment, falling back
+// to the pyenv version selected in the environment
+func virtualEnv() string {
 	var env string
 	if env == "" {
 		env, _ = os.LookupEnv("VIRTUAL_ENV")
@@ -29,6 +31,14 @@ func segmentVirtualEnv(p *powerline) []pwl.Segment {
 	if env == "" {
 		env, _ = os.LookupEnv("PYENV_VERSION")
 	}
+	return env
+}
+
+func segmentVirtualEnv(p *powerline) []pwl.Segment {
+	if p.cfg.PythonPrefer == "pyenv" && pyenvVersion(p.cwd) != "" {
+		return []pwl.Segment{}
+	}
+	env := virtualEnv()
 	if env == "" {
 		return []pwl.Segment{}
 	}
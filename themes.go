@@ -1,5 +1,10 @@
 package main
 
+import (
+	"reflect"
+	"strings"
+)
+
 // Symbols of the theme
 type SymbolTemplate struct {
 	Lock                 string
@@ -23,6 +28,77 @@ type SymbolTemplate struct {
 	VenvIndicator string
 	NodeIndicator string
 	RvmIndicator  string
+
+	RepoSyncAhead    string
+	RepoSyncBehind   string
+	RepoSyncDiverged string
+	RepoSynced       string
+
+	RepoIgnored string
+
+	RepoSubmodules string
+
+	RepoPushAhead  string
+	RepoPushBehind string
+
+	RepoTypeBare      string
+	RepoTypeSubmodule string
+	RepoTypeWorktree  string
+	RepoTypeSparse    string
+
+	SSHAgent string
+
+	RepoRemote string
+
+	RepoEmpty string
+
+	RepoInGitDir string
+
+	Todo string
+
+	RepoBehindDefault string
+
+	Editor string
+
+	RepoPushTarget string
+
+	RepoStashNamed string
+
+	BuildOk     string
+	BuildFailed string
+
+	RepoUnpushed string
+
+	PyenvIndicator string
+
+	RepoRecursive string
+
+	RepoGCNeeded string
+
+	Azure string
+
+	RepoLocal string
+
+	GithubPullRequests string
+
+	RepoChanges string
+
+	RepoSymbolicRef string
+
+	LastCommand string
+
+	RepoDot string
+
+	RepoIndexLocked string
+
+	MuxZoomed   string
+	MuxCopyMode string
+
+	Container string
+
+	RepoRemotes string
+
+	RepoBisect string
 }
 
 // Theme definitions
@@ -54,6 +130,10 @@ type Theme struct {
 	PathBg             uint8
 	CwdFg              uint8
 	SeparatorFg        uint8
+	// SeparatorOverride draws the separators between segments in
+	// SeparatorOverrideFg rather than the background of the segment before
+	SeparatorOverride   bool
+	SeparatorOverrideFg uint8
 
 	ReadonlyFg uint8
 	ReadonlyBg uint8
@@ -144,8 +224,8 @@ type Theme struct {
 	NodeVersionFg uint8
 	NodeVersionBg uint8
 
-	RvmFg        uint8
-	RvmBg        uint8
+	RvmFg uint8
+	RvmBg uint8
 
 	LoadFg           uint8
 	LoadBg           uint8
@@ -161,6 +241,176 @@ type Theme struct {
 
 	ViModeCommandFg uint8
 	ViModeCommandBg uint8
-	ViModeInsertFg uint8
-	ViModeInsertBg uint8
+	ViModeInsertFg  uint8
+	ViModeInsertBg  uint8
+
+	GitSyncAheadFg    uint8
+	GitSyncAheadBg    uint8
+	GitSyncBehindFg   uint8
+	GitSyncBehindBg   uint8
+	GitSyncDivergedFg uint8
+	GitSyncDivergedBg uint8
+	GitSyncedFg       uint8
+	GitSyncedBg       uint8
+
+	GitIgnoredFg uint8
+	GitIgnoredBg uint8
+
+	AsdfFg uint8
+	AsdfBg uint8
+
+	GitSubmodulesFg uint8
+	GitSubmodulesBg uint8
+
+	GitPushAheadFg  uint8
+	GitPushAheadBg  uint8
+	GitPushBehindFg uint8
+	GitPushBehindBg uint8
+
+	SSHAgentFg      uint8
+	SSHAgentBg      uint8
+	SSHAgentEmptyFg uint8
+	SSHAgentEmptyBg uint8
+
+	GitRemoteFg uint8
+	GitRemoteBg uint8
+
+	VaultFg        uint8
+	VaultBg        uint8
+	VaultInvalidFg uint8
+	VaultInvalidBg uint8
+
+	CmdStickyFg uint8
+	CmdStickyBg uint8
+
+	CwdGradientStartFg uint8
+	CwdGradientEndFg   uint8
+
+	TodoFg uint8
+	TodoBg uint8
+
+	GitBehindDefaultFg uint8
+	GitBehindDefaultBg uint8
+
+	EditorFg uint8
+	EditorBg uint8
+
+	GitPushTargetFg uint8
+	GitPushTargetBg uint8
+
+	GitAgeFg uint8
+	GitAgeBg uint8
+
+	GitUnpushedFg uint8
+	GitUnpushedBg uint8
+
+	GitConflictProminentFg uint8
+	GitConflictProminentBg uint8
+
+	PyenvFg uint8
+	PyenvBg uint8
+
+	GitGCHintFg uint8
+	GitGCHintBg uint8
+
+	AzureFg uint8
+	AzureBg uint8
+
+	GithubFg uint8
+	GithubBg uint8
+
+	GitMergeBaseFg uint8
+	GitMergeBaseBg uint8
+
+	GitNestedFg uint8
+	GitNestedBg uint8
+
+	FillFg uint8
+	FillBg uint8
+
+	LastCommandFg uint8
+	LastCommandBg uint8
+
+	MuxStateFg uint8
+	MuxStateBg uint8
+
+	ContainerFg uint8
+	ContainerBg uint8
+
+	GitCommitSubjectFg uint8
+	GitCommitSubjectBg uint8
+
+	GitOperationFg uint8
+	GitOperationBg uint8
+
+	GitRemoteCountFg uint8
+	GitRemoteCountBg uint8
+}
+
+// withFallbackColors returns the theme with every foreground and background
+// color that isSet reports as unset replaced by fg and bg, unless they are nil.
+// As 0 is a valid color, themes loaded from JSON use the absence of a key
+// instead.
+func (t Theme) withFallbackColors(isSet func(field string) bool, fg, bg *uint8) Theme {
+	value := reflect.ValueOf(&t).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Type.Kind() != reflect.Uint8 || isSet(field.Name) {
+			continue
+		}
+		switch {
+		case fg != nil && strings.HasSuffix(field.Name, "Fg"):
+			value.Field(i).SetUint(uint64(*fg))
+		case bg != nil && strings.HasSuffix(field.Name, "Bg"):
+			value.Field(i).SetUint(uint64(*bg))
+		}
+	}
+	return t
+}
+
+// withGitStatsBg returns the theme with the backgrounds of all git status
+// segments replaced by bg
+func (t Theme) withGitStatsBg(bg uint8) Theme {
+	t.GitAheadBg = bg
+	t.GitBehindBg = bg
+	t.GitStagedBg = bg
+	t.GitNotStagedBg = bg
+	t.GitUntrackedBg = bg
+	t.GitConflictedBg = bg
+	t.GitStashedBg = bg
+	t.GitSyncAheadBg = bg
+	t.GitSyncBehindBg = bg
+	t.GitSyncDivergedBg = bg
+	t.GitSyncedBg = bg
+	t.GitIgnoredBg = bg
+	t.GitSubmodulesBg = bg
+	t.GitPushAheadBg = bg
+	t.GitPushBehindBg = bg
+	t.GitBehindDefaultBg = bg
+	return t
+}
+
+// withGitOverrides returns the symbols with the git symbols replaced by the
+// ones set on the command line or in the config file
+func (s SymbolTemplate) withGitOverrides(cfg Config) SymbolTemplate {
+	overrides := []struct {
+		symbol   *string
+		override string
+	}{
+		{&s.RepoBranch, cfg.GitSymbolBranch},
+		{&s.RepoDetached, cfg.GitSymbolDetached},
+		{&s.RepoAhead, cfg.GitSymbolAhead},
+		{&s.RepoBehind, cfg.GitSymbolBehind},
+		{&s.RepoStaged, cfg.GitSymbolStaged},
+		{&s.RepoNotStaged, cfg.GitSymbolNotStaged},
+		{&s.RepoUntracked, cfg.GitSymbolUntracked},
+		{&s.RepoConflicted, cfg.GitSymbolConflicted},
+		{&s.RepoStashed, cfg.GitSymbolStashed},
+	}
+	for _, o := range overrides {
+		if o.override != "" {
+			*o.symbol = o.override
+		}
+	}
+	return s
 }